@@ -0,0 +1,246 @@
+package codeownerizer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	"gopkg.in/yaml.v3"
+)
+
+// Aliases maps logical owners, such as @backend, to the teams, users, emails
+// or other aliases they stand for. Keys are lowercase, since owners are
+// case-insensitive.
+type Aliases map[string][]string
+
+// AliasCycleError is returned when aliases refer to each other in a cycle.
+type AliasCycleError struct {
+	// Cycle lists the aliases of the cycle, starting and ending with the same
+	// alias.
+	Cycle []string
+}
+
+func (e *AliasCycleError) Error() string {
+	return fmt.Sprintf("alias cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// ReadAliasFile reads an alias file, a YAML map from each alias to the list of
+// owners it stands for:
+//
+//	"@backend":
+//	  - "@org/api"
+//	  - "@payments"
+//	"@payments":
+//	  - "@alice"
+//
+// A missing file defines no aliases.
+func ReadAliasFile(path string) (Aliases, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := ParseAliases(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return aliases, nil
+}
+
+// ParseAliases parses the content of an alias file, and checks that every
+// alias and owner is well-formed and that no alias refers back to itself.
+func ParseAliases(data []byte) (Aliases, error) {
+	var raw map[string][]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	aliases := make(Aliases, len(raw))
+	for alias, owners := range raw {
+		if !strings.HasPrefix(alias, "@") || len(alias) == 1 {
+			return nil, fmt.Errorf("invalid alias: %s", alias)
+		}
+		if len(owners) == 0 {
+			return nil, fmt.Errorf("%s stands for no owner", alias)
+		}
+		for _, owner := range owners {
+			if !strings.Contains(owner, "@") || strings.ContainsAny(owner, " \t") || owner == "@" {
+				return nil, fmt.Errorf("%s: invalid owner: %s", alias, owner)
+			}
+		}
+		key := strings.ToLower(alias)
+		if _, ok := aliases[key]; ok {
+			return nil, fmt.Errorf("%s is defined twice", alias)
+		}
+		aliases[key] = owners
+	}
+	if err := aliases.Validate(); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// Validate reports the first cycle among the aliases, in alphabetical order.
+func (a Aliases) Validate() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(a))
+	var path []string
+	var visit func(alias string) error
+	visit = func(alias string) error {
+		switch state[alias] {
+		case visiting:
+			for i, p := range path {
+				if p == alias {
+					return &AliasCycleError{Cycle: append(append([]string{}, path[i:]...), alias)}
+				}
+			}
+		case done:
+			return nil
+		}
+		state[alias] = visiting
+		path = append(path, alias)
+		for _, owner := range a[alias] {
+			if _, ok := a[strings.ToLower(owner)]; ok {
+				if err := visit(strings.ToLower(owner)); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[alias] = done
+		return nil
+	}
+
+	keys := make([]string, 0, len(a))
+	for alias := range a {
+		keys = append(keys, alias)
+	}
+	sort.Strings(keys)
+	for _, alias := range keys {
+		if err := visit(alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve returns the owners the owner stands for, expanding nested aliases,
+// or the owner itself when it is not an alias. Owners are listed once, in the
+// order they are first reached.
+func (a Aliases) Resolve(owner string) ([]string, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a.resolve(owner), nil
+}
+
+// resolve expands the owner, assuming there are no cycles.
+func (a Aliases) resolve(owner string) []string {
+	var resolved []string
+	seen := make(map[string]bool)
+	var expand func(owner string)
+	expand = func(owner string) {
+		if owners, ok := a[strings.ToLower(owner)]; ok {
+			for _, o := range owners {
+				expand(o)
+			}
+			return
+		}
+		if !seen[strings.ToLower(owner)] {
+			seen[strings.ToLower(owner)] = true
+			resolved = append(resolved, owner)
+		}
+	}
+	expand(owner)
+	return resolved
+}
+
+// ExpandRuleset replaces the aliases among the owners of every rule with the
+// owners they stand for.
+func (a Aliases) ExpandRuleset(ruleset codeowners.Ruleset) (codeowners.Ruleset, error) {
+	return a.expandRuleset(ruleset, func(string) bool { return false })
+}
+
+// expandRuleset expands the rules, leaving out the aliases that are skipped
+// along with the owners they stand for.
+func (a Aliases) expandRuleset(ruleset codeowners.Ruleset, skip func(alias string) bool) (codeowners.Ruleset, error) {
+	if len(a) == 0 {
+		return ruleset, nil
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	expanded := make(codeowners.Ruleset, 0, len(ruleset))
+	for _, rule := range ruleset {
+		rule.Owners = a.expandOwners(rule.Owners, skip)
+		expanded = append(expanded, rule)
+	}
+	return expanded, nil
+}
+
+// expandOwners replaces the aliases among the owners with the owners they
+// stand for, assuming there are no cycles.
+func (a Aliases) expandOwners(owners []codeowners.Owner, skip func(alias string) bool) []codeowners.Owner {
+	if len(a) == 0 {
+		return owners
+	}
+	var expanded []codeowners.Owner
+	seen := make(map[string]bool)
+	for _, owner := range owners {
+		if _, ok := a[strings.ToLower(owner.String())]; ok && skip(owner.String()) {
+			continue
+		}
+		for _, r := range a.resolve(owner.String()) {
+			if seen[strings.ToLower(r)] {
+				continue
+			}
+			seen[strings.ToLower(r)] = true
+			expanded = append(expanded, newOwner(r))
+		}
+	}
+	return expanded
+}
+
+// sources maps the owners reached through the aliases listed in the ruleset,
+// keyed by lowercase owner, to those aliases as written in CODEOWNERS. Aliases
+// that are skipped are left out.
+func (a Aliases) sources(ruleset codeowners.Ruleset, skip func(alias string) bool) map[string][]string {
+	sources := make(map[string][]string)
+	for _, rule := range ruleset {
+		for _, owner := range rule.Owners {
+			alias := owner.String()
+			if _, ok := a[strings.ToLower(alias)]; !ok || skip(alias) {
+				continue
+			}
+			for _, r := range a.resolve(alias) {
+				key := strings.ToLower(r)
+				if !slices.Contains(sources[key], alias) {
+					sources[key] = append(sources[key], alias)
+				}
+			}
+		}
+	}
+	return sources
+}
+
+// newOwner parses an owner as written in CODEOWNERS.
+func newOwner(owner string) codeowners.Owner {
+	switch {
+	case !strings.HasPrefix(owner, "@"):
+		return codeowners.Owner{Value: owner, Type: codeowners.EmailOwner}
+	case strings.Contains(owner, "/"):
+		return codeowners.Owner{Value: owner[1:], Type: codeowners.TeamOwner}
+	default:
+		return codeowners.Owner{Value: owner[1:], Type: codeowners.UsernameOwner}
+	}
+}
@@ -0,0 +1,88 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hmarr/codeowners"
+)
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases([]byte(`
+"@Backend":
+  - "@org/api"
+  - "@payments"
+"@payments":
+  - "@user1"
+  - "@org/api"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners, err := aliases.Resolve("@backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"@org/api", "@user1"}, owners); diff != "" {
+		t.Errorf("unexpected owners\n%s", diff)
+	}
+
+	_, err = ParseAliases([]byte(`
+"@a": ["@b"]
+"@b": ["@c", "@user1"]
+"@c": ["@a"]
+`))
+	var cycle *AliasCycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected a cycle, got %v", err)
+	}
+	if diff := cmp.Diff("alias cycle: @a -> @b -> @c -> @a", err.Error()); diff != "" {
+		t.Errorf("unexpected error\n%s", diff)
+	}
+
+	for content, want := range map[string]string{
+		`"backend": ["@org/api"]`: "invalid alias: backend",
+		`"@backend": []`:          "@backend stands for no owner",
+		`"@backend": ["api"]`:     "@backend: invalid owner: api",
+	} {
+		if _, err := ParseAliases([]byte(content)); err == nil || err.Error() != want {
+			t.Errorf("ParseAliases(%q) returned %v, want %s", content, err, want)
+		}
+	}
+}
+
+func TestPlanRulesetWithAliases(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @backend\n/docs/ @writers @user3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := ParseAliases([]byte(`
+"@backend": ["@org/api", "@payments"]
+"@payments": ["@user1"]
+"@writers": ["@user2"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &memoryBackend{
+		teams:         map[string]string{},
+		collaborators: map[string]string{"user3": "write"},
+	}
+	opts := &Options{Backend: backend, Aliases: aliases, IgnoredOwners: []string{"@writers"}}
+
+	actions, err := PlanRuleset(context.Background(), nil, "org", "repo", ruleset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []RuleRef{{Pattern: "*", LineNumber: 1}}
+	want := []Action{
+		{Type: ActionGrantTeam, Owner: "@org/api", Principal: "api", Permission: "push", Rules: rules},
+		{Type: ActionGrantUser, Owner: "@user1", Principal: "user1", Permission: "push", Rules: rules},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+}
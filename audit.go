@@ -0,0 +1,185 @@
+package codeownerizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// AuditEvent is a record of a permission change.
+type AuditEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Actor is the login the change was made as.
+	Actor     string `json:"actor"`
+	TokenType string `json:"token_type"`
+	// Repo is the repository in the owner/name form.
+	Repo      string `json:"repo"`
+	Action    string `json:"action"`
+	Principal string `json:"principal"`
+	// Team is the team the principal was added to by ActionAddTeamMember.
+	Team          string `json:"team,omitempty"`
+	OldPermission string `json:"old_permission"`
+	NewPermission string `json:"new_permission"`
+	// Reason names the CODEOWNERS owner and the rules the change was made for,
+	// or explains a change no rule asked for.
+	Reason string    `json:"reason"`
+	Rules  []RuleRef `json:"rules,omitempty"`
+}
+
+// AuditSink stores audit events.
+type AuditSink interface {
+	Write(ctx context.Context, event AuditEvent) error
+}
+
+// Auditor records permission changes to its sinks.
+type Auditor struct {
+	Actor     string
+	TokenType string
+	Sinks     []AuditSink
+}
+
+// Record writes an event for the applied action to every sink. Failing to
+// write to a sink is logged, as the change has already been made.
+func (a *Auditor) Record(ctx context.Context, org string, repo string, action Action) {
+	event := newAuditEvent(a, org, repo, action, time.Now().UTC())
+	for _, sink := range a.Sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			log.Printf("failed to write an audit event: %s\n", err.Error())
+		}
+	}
+}
+
+func newAuditEvent(a *Auditor, org string, repo string, action Action, timestamp time.Time) AuditEvent {
+	newPermission := action.Permission
+	if action.Type == ActionAddTeamMember {
+		newPermission = "member"
+	}
+
+	reason := "code owner " + action.Owner
+	if action.Reason != "" {
+		reason = action.Reason
+	}
+	if len(action.Rules) > 0 {
+		var rules []string
+		for _, rule := range action.Rules {
+			rules = append(rules, rule.String())
+		}
+		reason += " on CODEOWNERS " + strings.Join(rules, ", ")
+	}
+
+	return AuditEvent{
+		Timestamp:     timestamp,
+		Actor:         a.Actor,
+		TokenType:     a.TokenType,
+		Repo:          org + "/" + repo,
+		Action:        action.Type,
+		Principal:     action.Principal,
+		Team:          action.Team,
+		OldPermission: action.OldPermission,
+		NewPermission: newPermission,
+		Reason:        reason,
+		Rules:         action.Rules,
+	}
+}
+
+// TokenType tells the kind of a GitHub token from its prefix.
+func TokenType(token string) string {
+	switch {
+	case strings.HasPrefix(token, "github_pat_"):
+		return "fine_grained_personal_access_token"
+	case strings.HasPrefix(token, "ghp_"):
+		return "personal_access_token"
+	case strings.HasPrefix(token, "gho_"):
+		return "oauth_access_token"
+	case strings.HasPrefix(token, "ghu_"):
+		return "github_app_user_access_token"
+	case strings.HasPrefix(token, "ghs_"):
+		return "github_app_installation_access_token"
+	default:
+		return "unknown"
+	}
+}
+
+// GetActor returns the login of the authenticated user. GitHub App
+// installation tokens are not a user, so it fails for them.
+func GetActor(ctx context.Context, api *github.Client) (string, error) {
+	user, _, err := api.Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// JSONLSink appends audit events to a file, one JSON object per line.
+type JSONLSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewJSONLSink opens the file for appending, creating it if needed.
+func NewJSONLSink(path string) (*JSONLSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLSink{f: f}, nil
+}
+
+func (s *JSONLSink) Write(ctx context.Context, event AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	// Events must survive a crash right after the change.
+	return s.f.Sync()
+}
+
+func (s *JSONLSink) Close() error {
+	return s.f.Close()
+}
+
+// WebhookSink posts each audit event as JSON to a URL.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink returns a sink posting to the URL with http.DefaultClient.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, Client: http.DefaultClient}
+}
+
+func (s *WebhookSink) Write(ctx context.Context, event AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook %s responded with %s", s.URL, resp.Status)
+	}
+	return nil
+}
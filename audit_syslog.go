@@ -0,0 +1,35 @@
+//go:build !windows && !plan9
+
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogSink writes audit events as JSON to the system logger.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the system logger with the tag.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, event AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(b))
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
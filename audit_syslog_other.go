@@ -0,0 +1,24 @@
+//go:build windows || plan9
+
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+)
+
+// SyslogSink is not supported on this platform.
+type SyslogSink struct{}
+
+// NewSyslogSink always fails, because there is no system logger.
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+func (s *SyslogSink) Write(ctx context.Context, event AuditEvent) error {
+	return errors.New("syslog is not supported on this platform")
+}
+
+func (s *SyslogSink) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package codeownerizer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAuditor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	jsonl, err := NewJSONLSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var posted []AuditEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event AuditEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		posted = append(posted, event)
+	}))
+	defer server.Close()
+
+	auditor := &Auditor{
+		Actor:     "octocat",
+		TokenType: TokenType("ghs_xxxx"),
+		Sinks:     []AuditSink{jsonl, NewWebhookSink(server.URL)},
+	}
+	actions := []Action{
+		{
+			Type:          ActionGrantTeam,
+			Owner:         "@octo-org/octocats",
+			Principal:     "octocats",
+			Permission:    "push",
+			OldPermission: "pull",
+			Rules: []RuleRef{
+				{Pattern: "*", LineNumber: 1},
+				{Pattern: "*.go", LineNumber: 3},
+			},
+		},
+		{
+			Type:      ActionAddTeamMember,
+			Owner:     "@octocat",
+			Principal: "octocat",
+			Team:      "codeowners",
+		},
+	}
+	for _, action := range actions {
+		auditor.Record(context.Background(), "org", "repo", action)
+	}
+	if err := jsonl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var written []AuditEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Timestamp.IsZero() {
+			t.Errorf("expected a timestamp")
+		}
+		event.Timestamp = time.Time{}
+		written = append(written, event)
+	}
+
+	want := []AuditEvent{
+		{
+			Actor:         "octocat",
+			TokenType:     "github_app_installation_access_token",
+			Repo:          "org/repo",
+			Action:        ActionGrantTeam,
+			Principal:     "octocats",
+			OldPermission: "pull",
+			NewPermission: "push",
+			Reason:        "code owner @octo-org/octocats on CODEOWNERS line 1 (*), line 3 (*.go)",
+			Rules: []RuleRef{
+				{Pattern: "*", LineNumber: 1},
+				{Pattern: "*.go", LineNumber: 3},
+			},
+		},
+		{
+			Actor:         "octocat",
+			TokenType:     "github_app_installation_access_token",
+			Repo:          "org/repo",
+			Action:        ActionAddTeamMember,
+			Principal:     "octocat",
+			Team:          "codeowners",
+			NewPermission: "member",
+			Reason:        "code owner @octocat",
+		},
+	}
+	if diff := cmp.Diff(want, written); diff != "" {
+		t.Errorf("unexpected events in the file\n%s", diff)
+	}
+
+	for i := range posted {
+		posted[i].Timestamp = time.Time{}
+	}
+	if diff := cmp.Diff(want, posted); diff != "" {
+		t.Errorf("unexpected events posted\n%s", diff)
+	}
+}
@@ -0,0 +1,168 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// Backend manages the access to repositories. Planning and applying grants
+// only go through a Backend, so that they can run against something other
+// than the REST API, such as a recorded or an in-memory backend in tests.
+type Backend interface {
+	AccessLister
+	// GrantTeam gives the team the permission on the repository.
+	GrantTeam(ctx context.Context, org string, repo string, slug string, permission string) error
+	// GrantUser adds the user to the repository as a collaborator with the
+	// permission.
+	GrantUser(ctx context.Context, org string, repo string, login string, permission string) error
+	// AddTeamMember adds the user to the team as a member.
+	AddTeamMember(ctx context.Context, org string, slug string, login string) error
+	// RevokeTeam removes the access of the team to the repository.
+	RevokeTeam(ctx context.Context, org string, repo string, slug string) error
+	// RevokeUser removes the user from the collaborators of the repository.
+	RevokeUser(ctx context.Context, org string, repo string, login string) error
+}
+
+// TeamHierarchyBackend is a Backend that knows the child teams of a team.
+// Without it, teams do not inherit the permissions of their parents.
+type TeamHierarchyBackend interface {
+	ListChildTeams(ctx context.Context, org string, slug string) ([]*github.Team, error)
+}
+
+// CustomRoleBackend is a Backend that knows the custom repository roles of an
+// organization. Without it, only base permissions can be granted.
+type CustomRoleBackend interface {
+	ListCustomRepoRoles(ctx context.Context, org string) ([]*github.CustomRepoRoles, error)
+}
+
+// PermissionLevelBackend is a Backend that reports the effective permission of
+// a team or a user on a repository. It is required by the accurate mode.
+type PermissionLevelBackend interface {
+	GetTeamPermission(ctx context.Context, org string, repo string, slug string) (*Permission, error)
+	GetUserPermission(ctx context.Context, org string, repo string, login string) (*Permission, error)
+}
+
+// TeamMembershipBackend is a Backend that reports whether a user is an active
+// member of a team. Without it, users are never treated as sufficient through
+// their teams.
+type TeamMembershipBackend interface {
+	IsActiveTeamMember(ctx context.Context, org string, slug string, login string) (bool, error)
+}
+
+// TeamMembersBackend is a Backend that lists the members of a team and looks
+// up users. Without it, teams are assumed to have active members when
+// analyzing who can approve reviews.
+type TeamMembersBackend interface {
+	// ListTeamMembers lists the members of the team, including the members of
+	// its child teams.
+	ListTeamMembers(ctx context.Context, org string, slug string) ([]*github.User, error)
+	GetUser(ctx context.Context, login string) (*github.User, error)
+}
+
+// InvitationBackend is a Backend that knows the pending invitations to
+// collaborate on a repository. Without it, invited users who have not accepted
+// yet count as lacking access when verifying convergence.
+type InvitationBackend interface {
+	// ListInvitations maps the logins of the invited users to the permission
+	// they were invited with.
+	ListInvitations(ctx context.Context, org string, repo string) (map[string]string, error)
+}
+
+// RESTBackend is a Backend using the REST API. It supports all the optional
+// capabilities.
+type RESTBackend struct {
+	*RESTLister
+}
+
+func NewRESTBackend(api *github.Client) *RESTBackend {
+	return &RESTBackend{RESTLister: NewRESTLister(api)}
+}
+
+func (b *RESTBackend) GrantTeam(ctx context.Context, org string, repo string, slug string, permission string) error {
+	resp, err := b.api.Teams.AddTeamRepoBySlug(ctx, org, slug, org, repo, &github.TeamAddTeamRepoOptions{
+		Permission: permission,
+	})
+	return checkResponse(resp, err)
+}
+
+func (b *RESTBackend) GrantUser(ctx context.Context, org string, repo string, login string, permission string) error {
+	_, resp, err := b.api.Repositories.AddCollaborator(ctx, org, repo, login, &github.RepositoryAddCollaboratorOptions{
+		Permission: permission,
+	})
+	return checkResponse(resp, err)
+}
+
+func (b *RESTBackend) AddTeamMember(ctx context.Context, org string, slug string, login string) error {
+	_, resp, err := b.api.Teams.AddTeamMembershipBySlug(ctx, org, slug, login, &github.TeamAddTeamMembershipOptions{
+		Role: "member",
+	})
+	return checkResponse(resp, err)
+}
+
+func (b *RESTBackend) RevokeTeam(ctx context.Context, org string, repo string, slug string) error {
+	resp, err := b.api.Teams.RemoveTeamRepoBySlug(ctx, org, slug, org, repo)
+	return checkResponse(resp, err)
+}
+
+func (b *RESTBackend) RevokeUser(ctx context.Context, org string, repo string, login string) error {
+	resp, err := b.api.Repositories.RemoveCollaborator(ctx, org, repo, login)
+	return checkResponse(resp, err)
+}
+
+func (b *RESTBackend) ListChildTeams(ctx context.Context, org string, slug string) ([]*github.Team, error) {
+	return ListChildTeams(ctx, b.api, org, slug)
+}
+
+func (b *RESTBackend) ListCustomRepoRoles(ctx context.Context, org string) ([]*github.CustomRepoRoles, error) {
+	return ListCustomRepoRoles(ctx, b.api, org)
+}
+
+func (b *RESTBackend) GetTeamPermission(ctx context.Context, org string, repo string, slug string) (*Permission, error) {
+	return GetTeamPermission(ctx, b.api, org, repo, slug)
+}
+
+func (b *RESTBackend) GetUserPermission(ctx context.Context, org string, repo string, login string) (*Permission, error) {
+	return GetUserPermission(ctx, b.api, org, repo, login)
+}
+
+func (b *RESTBackend) IsActiveTeamMember(ctx context.Context, org string, slug string, login string) (bool, error) {
+	membership, resp, err := b.api.Teams.GetTeamMembershipBySlug(ctx, org, slug, login)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return stringify(membership.State) == "active", nil
+}
+
+func (b *RESTBackend) ListTeamMembers(ctx context.Context, org string, slug string) ([]*github.User, error) {
+	return ListTeamMembers(ctx, b.api, org, slug)
+}
+
+func (b *RESTBackend) GetUser(ctx context.Context, login string) (*github.User, error) {
+	user, _, err := b.api.Users.Get(ctx, login)
+	return user, err
+}
+
+func (b *RESTBackend) ListInvitations(ctx context.Context, org string, repo string) (map[string]string, error) {
+	invitations, err := ListInvitations(ctx, b.api, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	invited := make(map[string]string, len(invitations))
+	for _, invitation := range invitations {
+		invited[strings.ToLower(invitation.GetInvitee().GetLogin())] = invitation.GetPermissions()
+	}
+	return invited, nil
+}
+
+func checkResponse(resp *github.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return github.CheckResponse(resp.Response)
+}
@@ -0,0 +1,201 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// memoryBackend is a Backend keeping the access to a single repository in
+// memory.
+type memoryBackend struct {
+	teams         map[string]string
+	collaborators map[string]string
+	children      map[string][]string
+	emails        map[string][]string
+	members       map[string][]string
+}
+
+func (b *memoryBackend) ListTeams(ctx context.Context, org string, repo string) ([]*github.Team, error) {
+	var teams []*github.Team
+	for slug, permission := range b.teams {
+		teams = append(teams, &github.Team{Slug: github.Ptr(slug), Permission: github.Ptr(permission), Permissions: permissionsOf(strings.ToUpper(permission))})
+	}
+	return teams, nil
+}
+
+func (b *memoryBackend) ListCollaborators(ctx context.Context, org string, repo string) ([]*github.User, error) {
+	var collaborators []*github.User
+	for login, permission := range b.collaborators {
+		collaborators = append(collaborators, &github.User{Login: github.Ptr(login), Permissions: permissionsOf(strings.ToUpper(permission))})
+	}
+	return collaborators, nil
+}
+
+func (b *memoryBackend) ResolveEmails(ctx context.Context, emails []string) (map[string][]string, error) {
+	return b.emails, nil
+}
+
+func (b *memoryBackend) GrantTeam(ctx context.Context, org string, repo string, slug string, permission string) error {
+	b.teams[slug] = "write"
+	return nil
+}
+
+func (b *memoryBackend) GrantUser(ctx context.Context, org string, repo string, login string, permission string) error {
+	b.collaborators[login] = "write"
+	return nil
+}
+
+func (b *memoryBackend) AddTeamMember(ctx context.Context, org string, slug string, login string) error {
+	return nil
+}
+
+func (b *memoryBackend) RevokeTeam(ctx context.Context, org string, repo string, slug string) error {
+	delete(b.teams, slug)
+	return nil
+}
+
+func (b *memoryBackend) RevokeUser(ctx context.Context, org string, repo string, login string) error {
+	delete(b.collaborators, login)
+	return nil
+}
+
+func (b *memoryBackend) ListChildTeams(ctx context.Context, org string, slug string) ([]*github.Team, error) {
+	var children []*github.Team
+	for _, child := range b.children[slug] {
+		children = append(children, &github.Team{Slug: github.Ptr(child)})
+	}
+	return children, nil
+}
+
+func (b *memoryBackend) ListTeamMembers(ctx context.Context, org string, slug string) ([]*github.User, error) {
+	var members []*github.User
+	for _, login := range b.members[slug] {
+		members = append(members, &github.User{Login: github.Ptr(login)})
+	}
+	return members, nil
+}
+
+func (b *memoryBackend) GetUser(ctx context.Context, login string) (*github.User, error) {
+	return &github.User{Login: github.Ptr(login)}, nil
+}
+
+func TestBackend(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @org/child @org/team2 @user1 user2@example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &memoryBackend{
+		teams:         map[string]string{"parent": "write", "team2": "read"},
+		collaborators: map[string]string{"user1": "read"},
+		children:      map[string][]string{"parent": {"child"}},
+		emails:        map[string][]string{"user2@example.com": {"user2"}},
+	}
+	opts := &Options{Backend: backend}
+	ctx := context.Background()
+
+	// No client is needed when the backend is set.
+	actions, err := PlanRuleset(ctx, nil, "org", "repo", ruleset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []RuleRef{{Pattern: "*", LineNumber: 1}}
+	want := []Action{
+		{Type: ActionGrantTeam, Owner: "@org/team2", Principal: "team2", Permission: "push", OldPermission: "read", Rules: rules},
+		{Type: ActionGrantUser, Owner: "@user1", Principal: "user1", Permission: "push", OldPermission: "pull", Rules: rules},
+		{Type: ActionGrantUser, Owner: "user2@example.com", Principal: "user2", Permission: "push", Rules: rules},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+
+	if err := Reconcile(ctx, nil, "org", "repo", ruleset, opts); err != nil {
+		t.Fatal(err)
+	}
+	actions, err = PlanRuleset(ctx, nil, "org", "repo", ruleset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no actions after reconciling, got %v", actions)
+	}
+}
+
+func TestAnalyzeRulesWithBackend(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @org/child @user1\n/docs/ @org/team2\n/empty/ @org/empty\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &memoryBackend{
+		teams:         map[string]string{"parent": "write", "team2": "read", "empty": "write"},
+		collaborators: map[string]string{"user1": "read"},
+		children:      map[string][]string{"parent": {"child"}},
+		members:       map[string][]string{"child": {"user3"}},
+	}
+
+	// No client is needed when the backend is set.
+	reports, err := AnalyzeRules(context.Background(), nil, "org", "repo", ruleset, &Options{Backend: backend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RuleReport{
+		{
+			Pattern:    "*",
+			LineNumber: 1,
+			Owners: []OwnerReport{
+				{Owner: "@org/child", CanApprove: true},
+				{Owner: "@user1", Reason: "user does not have the push permission"},
+			},
+			Approvable: true,
+		},
+		{
+			Pattern:    "/docs/",
+			LineNumber: 2,
+			Owners:     []OwnerReport{{Owner: "@org/team2", Reason: "team does not have the push permission"}},
+			Blocked:    true,
+		},
+		{
+			Pattern:    "/empty/",
+			LineNumber: 3,
+			Owners:     []OwnerReport{{Owner: "@org/empty", Reason: "team has no members", Memberless: true}},
+			Blocked:    true,
+		},
+	}
+	if diff := cmp.Diff(want, reports); diff != "" {
+		t.Errorf("unexpected reports\n%s", diff)
+	}
+}
+
+func TestVerifyPlanWithBackend(t *testing.T) {
+	backend := &memoryBackend{
+		teams:         map[string]string{"team1": "read"},
+		collaborators: map[string]string{"user1": "write"},
+	}
+	file := &CodeownersFile{Path: "CODEOWNERS", Content: []byte("* @org/team1 @user1\n")}
+	file.SHA = gitBlobSHA(file.Content)
+	ctx := context.Background()
+	opts := &Options{Backend: backend}
+
+	// The state is read through the backend, so no client is needed.
+	plan, err := NewPlan(ctx, nil, "org", "repo", file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPlan(ctx, nil, plan, file, opts); err != nil {
+		t.Errorf("expected the plan to be fresh, got %v", err)
+	}
+
+	backend.collaborators["user2"] = "read"
+	var stale *StalePlanError
+	if err := VerifyPlan(ctx, nil, plan, file, opts); !errors.As(err, &stale) {
+		t.Fatalf("expected a stale plan, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"collaborator user2 was added with pull"}, stale.Reasons); diff != "" {
+		t.Errorf("unexpected reasons\n%s", diff)
+	}
+}
@@ -0,0 +1,265 @@
+package codeownerizer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached response to a GET request.
+type CacheEntry struct {
+	StoredAt time.Time `json:"stored_at"`
+	// Response is the response serialized with http.Response.Write.
+	Response []byte `json:"response"`
+}
+
+// Cache stores responses keyed by request.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry) error
+}
+
+// MemoryCache is a Cache that lives as long as the process, which suits the
+// server and commands scanning many repositories.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*CacheEntry
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]*CacheEntry)}
+}
+
+func (c *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *MemoryCache) Set(key string, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return nil
+}
+
+// FileCache is a Cache that stores each entry as a file in a directory, so
+// that it is shared by repeated runs. Entries not written for MaxAge are
+// removed when other entries are written, at most once per MaxAge, so that the
+// directory does not grow with every repository ever scanned.
+type FileCache struct {
+	dir string
+	// MaxAge is how long an entry is kept since it was last stored or
+	// revalidated. Zero keeps entries forever.
+	MaxAge time.Duration
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+// NewFileCache returns a FileCache in the directory, creating it if needed.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *FileCache) Get(key string) (*CacheEntry, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *FileCache) Set(key string, entry *CacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// Entries are written to a temporary file first so that concurrent runs
+	// never read a partial entry.
+	f, err := os.CreateTemp(c.dir, "entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	c.prune()
+	return nil
+}
+
+// prune removes the entries, and the temporary files of interrupted writes,
+// that were last modified more than MaxAge ago. Entries are rewritten when
+// they are revalidated, so their modification time is when they were last
+// stored.
+func (c *FileCache) prune() {
+	if c.MaxAge <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	if now.Sub(c.pruned) < c.MaxAge {
+		c.mu.Unlock()
+		return
+	}
+	c.pruned = now
+	c.mu.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if now.Sub(info.ModTime()) > c.MaxAge {
+			os.Remove(filepath.Join(c.dir, entry.Name()))
+		}
+	}
+}
+
+// ttlCachedPaths match the API paths whose responses are reused without a
+// request while they are fresh: user searches, which resolve email owners, and
+// the teams of an organization and their child teams. They change rarely, and
+// the user search is heavily rate limited.
+var ttlCachedPaths = regexp.MustCompile(`^/(search/users|orgs/[^/]+/teams(/[^/]+/teams)?)$`)
+
+// CachingTransport is an http.RoundTripper caching responses to GET requests.
+// Cached responses are revalidated with their ETag, which GitHub does not
+// count against the rate limit when nothing changed. Responses of user
+// searches, organization teams and child teams are reused without
+// revalidation for TTL. GraphQL queries are POST requests and are never
+// cached, so the graphql backend resolves email owners without the cache.
+type CachingTransport struct {
+	Base  http.RoundTripper
+	Cache Cache
+	TTL   time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.base().RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	entry, cached := t.Cache.Get(key)
+	var cachedResp *http.Response
+	if cached {
+		resp, err := readCachedResponse(entry, req)
+		if err == nil {
+			cachedResp = resp
+		}
+	}
+
+	if cachedResp != nil && ttlCachedPaths.MatchString(req.URL.Path) && t.now().Sub(entry.StoredAt) < t.TTL {
+		return cachedResp, nil
+	}
+
+	if cachedResp != nil {
+		if etag := cachedResp.Header.Get("ETag"); etag != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", etag)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cachedResp != nil {
+		resp.Body.Close()
+		entry.StoredAt = t.now()
+		_ = t.Cache.Set(key, entry)
+		return cachedResp, nil
+	}
+
+	if resp.StatusCode == http.StatusOK && (resp.Header.Get("ETag") != "" || ttlCachedPaths.MatchString(req.URL.Path)) {
+		return t.store(key, resp)
+	}
+	return resp, nil
+}
+
+func (t *CachingTransport) store(key string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var b bytes.Buffer
+	stored := *resp
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	if err := stored.Write(&b); err == nil {
+		_ = t.Cache.Set(key, &CacheEntry{StoredAt: t.now(), Response: b.Bytes()})
+	}
+	return resp, nil
+}
+
+func (t *CachingTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *CachingTransport) now() time.Time {
+	if t.Now == nil {
+		return time.Now()
+	}
+	return t.Now()
+}
+
+// cacheKey identifies the request by its URL, credentials, as different
+// tokens may see different data, and Accept header, which selects the media
+// type of the response.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:8]) + " " + req.Header.Get("Accept") + " " + req.URL.String()
+}
+
+func readCachedResponse(entry *CacheEntry, req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(entry.Response)), req)
+}
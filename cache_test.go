@@ -0,0 +1,151 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+)
+
+func TestCachingTransport(t *testing.T) {
+	requests := map[string]int{}
+	notModified := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org/teams", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"slug":"team1"}]`))
+	})
+	mux.HandleFunc("/repos/org/repo/collaborators", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`[{"login":"octocat"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for name, cache := range map[string]Cache{"memory": NewMemoryCache(), "file": mustFileCache(t)} {
+		t.Run(name, func(t *testing.T) {
+			clear(requests)
+			notModified = 0
+			now := time.Now()
+			transport := &CachingTransport{Cache: cache, TTL: time.Hour, Now: func() time.Time { return now }}
+			c := github.NewClient(&http.Client{Transport: transport})
+			c.BaseURL, _ = url.Parse(server.URL + "/")
+			ctx := context.Background()
+
+			for i := 0; i < 2; i++ {
+				teams, _, err := c.Teams.ListTeams(ctx, "org", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff("team1", teams[0].GetSlug()); diff != "" {
+					t.Errorf("unexpected team\n%s", diff)
+				}
+				collaborators, err := ListCollaborators(ctx, c, "org", "repo")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff("octocat", collaborators[0].GetLogin()); diff != "" {
+					t.Errorf("unexpected collaborator\n%s", diff)
+				}
+			}
+			// Another media type is cached separately.
+			req, err := c.NewRequest(http.MethodGet, "orgs/org/teams", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", "application/vnd.github.raw+json")
+			if _, err := c.Do(ctx, req, nil); err != nil {
+				t.Fatal(err)
+			}
+			// The teams of the organization expire after the TTL.
+			now = now.Add(2 * time.Hour)
+			if _, _, err := c.Teams.ListTeams(ctx, "org", nil); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[string]int{"/orgs/org/teams": 3, "/repos/org/repo/collaborators": 2}
+			if diff := cmp.Diff(want, requests); diff != "" {
+				t.Errorf("unexpected requests\n%s", diff)
+			}
+			if diff := cmp.Diff(1, notModified); diff != "" {
+				t.Errorf("expected the collaborators to be revalidated\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFileCachePrune(t *testing.T) {
+	cache := mustFileCache(t)
+	cache.MaxAge = 24 * time.Hour
+
+	for _, key := range []string{"old", "recent"} {
+		if err := cache.Set(key, &CacheEntry{StoredAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An interrupted write leaves a temporary file behind.
+	tmp, err := os.CreateTemp(cache.dir, "entry-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{cache.path("old"), tmp.Name()} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Entries are pruned at most once per MaxAge, so the same run keeps them.
+	if err := cache.Set("new", &CacheEntry{StoredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get("old"); !ok {
+		t.Errorf("expected old to be kept until the next prune")
+	}
+
+	// The next run prunes them on its first write.
+	next, err := NewFileCache(cache.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.MaxAge = 24 * time.Hour
+	if err := next.Set("new", &CacheEntry{StoredAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, key := range []string{"old", "recent", "new"} {
+		if _, ok := next.Get(key); ok {
+			kept = append(kept, key)
+		}
+	}
+	if diff := cmp.Diff([]string{"recent", "new"}, kept); diff != "" {
+		t.Errorf("unexpected entries\n%s", diff)
+	}
+	if _, err := os.Stat(tmp.Name()); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary file to be removed, got %v", err)
+	}
+}
+
+func mustFileCache(t *testing.T) *FileCache {
+	t.Helper()
+	cache, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer"
+	"github.com/hmarr/codeowners"
+)
+
+var (
+	preferTeamAccess      bool
+	codeownersTeam        string
+	failOnMemberlessTeams bool
+	permission            string
+	interactive           bool
+	ignoreFile            string
+	ungrantableIssue      bool
+	fixUngrantable        bool
+	ownerMappingFile      string
+	verifyOnly            bool
+	failOnUnconverged     bool
+	reposFile             string
+)
+
+// registerGrantFlags adds the flags that decide which grants are made, shared
+// by the apply and plan commands.
+func registerGrantFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&preferTeamAccess, "prefer-team-access", false, "Treat user owners as sufficient when a team they belong to has the push permission")
+	fs.StringVar(&codeownersTeam, "codeowners-team", "", "Team slug to add user owners to instead of adding them as direct collaborators")
+	fs.StringVar(&permission, "permission", "push", "Permission or custom repository role to grant")
+	fs.StringVar(&ignoreFile, "ignore-file", ".codeownerizer-ignore", "File listing owners that are never granted. Owners declined in the interactive mode are appended to it")
+}
+
+// grantOptions builds the options from the grant flags.
+func grantOptions(client *github.Client) (*codeownerizer.Options, error) {
+	ignoredOwners, err := codeownerizer.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := codeownerizer.ReadAliasFile(aliasFile)
+	if err != nil {
+		return nil, err
+	}
+	return &codeownerizer.Options{
+		PreferTeamAccess: preferTeamAccess,
+		CodeownersTeam:   codeownersTeam,
+		Permission:       permission,
+		Accurate:         accurate,
+		IgnoredOwners:    ignoredOwners,
+		Lister:           newLister(client),
+		Aliases:          aliases,
+	}, nil
+}
+
+func runApply(args []string) error {
+	fs := newFlagSet("apply")
+	registerGrantFlags(fs)
+	fs.BoolVar(&failOnMemberlessTeams, "fail-on-memberless-teams", false, "Fail when a rule is owned only by teams without active members")
+	fs.BoolVar(&interactive, "interactive", false, "Ask for approval before granting each owner")
+	fs.BoolVar(&ungrantableIssue, "ungrantable-issue", false, "Open or update an issue listing the owners that cannot be granted")
+	fs.BoolVar(&fixUngrantable, "fix-ungrantable", false, "Open or update a pull request removing or replacing the owners that cannot be granted")
+	fs.StringVar(&ownerMappingFile, "owner-mapping", "", "File mapping owners that cannot be granted to their replacements for -fix-ungrantable")
+	fs.BoolVar(&verifyOnly, "verify-only", false, "Grant nothing and fail when any owner is below the target permission")
+	fs.BoolVar(&failOnUnconverged, "fail-on-unconverged", false, "Fail when any owner is still below the target permission after granting")
+	fs.StringVar(&reposFile, "repos-file", "", "YAML manifest listing the repositories to reconcile instead of the current one")
+	registerAuditFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	auditor, closeAuditor, err := newAuditor(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer closeAuditor()
+
+	if planFile := fs.Arg(0); planFile != "" {
+		return applyPlanFile(ctx, client, planFile, &codeownerizer.Options{Auditor: auditor, Lister: newLister(client)})
+	}
+
+	if reposFile != "" {
+		return applyManifest(ctx, client, auditor)
+	}
+
+	ruleset, err := codeowners.LoadFileFromStandardLocation()
+	if err != nil {
+		return err
+	}
+
+	setDefaultRepository()
+
+	opts, err := grantOptions(client)
+	if err != nil {
+		return err
+	}
+	opts.Auditor = auditor
+	var applied []codeownerizer.Action
+	if !verifyOnly {
+		if interactive {
+			applied, err = applyInteractively(ctx, client, ruleset, opts)
+		} else {
+			applied, err = codeownerizer.PlanRuleset(ctx, client, org, repo, ruleset, opts)
+			if err == nil {
+				codeownerizer.ApplyActions(ctx, client, org, repo, applied, opts)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if ungrantableIssue || fixUngrantable {
+			if err := reportUngrantableOwners(ctx, client, ruleset, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Read the access again, since a grant can succeed without taking effect
+	// and stale permission lists make every run repeat the same grants. When
+	// nothing was applied, the plan just computed found nothing to grant.
+	var convergence *codeownerizer.Convergence
+	if verifyOnly || len(applied) > 0 {
+		convergence, err = codeownerizer.VerifyConvergence(ctx, client, org, repo, ruleset, opts)
+	} else {
+		convergence, err = codeownerizer.PlanConvergence(ctx, client, org, repo, nil, opts)
+	}
+	if err != nil {
+		return err
+	}
+	reportConvergence(convergence)
+
+	var errs []error
+	if !convergence.Converged() && (verifyOnly || failOnUnconverged) {
+		errs = append(errs, fmt.Errorf("%d owner(s) are still below the target permission", len(convergence.Remaining)))
+	}
+	if failOnMemberlessTeams {
+		if err := checkMemberlessTeams(ctx, client, ruleset); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// postApplyFlags returns the set flags that check or fix the CODEOWNERS file of
+// the current repository once it is granted.
+func postApplyFlags() []string {
+	var set []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"-ungrantable-issue", ungrantableIssue},
+		{"-fix-ungrantable", fixUngrantable},
+		{"-fail-on-memberless-teams", failOnMemberlessTeams},
+	} {
+		if f.set {
+			set = append(set, f.name)
+		}
+	}
+	return set
+}
+
+// reportConvergence logs the owners left below the target permission and the
+// invited users who have not accepted yet.
+func reportConvergence(convergence *codeownerizer.Convergence) {
+	for _, action := range convergence.Pending {
+		log.Printf("%s has not accepted the invitation to the repo yet.\n", action.Owner)
+	}
+	for _, action := range convergence.Remaining {
+		log.Printf("%s is still below the target permission: %s\n", action.Owner, action)
+	}
+}
+
+// checkMemberlessTeams fails when a rule is owned only by teams without active
+// members.
+func checkMemberlessTeams(ctx context.Context, client *github.Client, ruleset codeowners.Ruleset) error {
+	analysis, err := analysisOptions(client)
+	if err != nil {
+		return err
+	}
+	reports, err := codeownerizer.AnalyzeRules(ctx, client, org, repo, ruleset, analysis)
+	if err != nil {
+		return err
+	}
+	memberless := codeownerizer.MemberlessRules(reports)
+	for _, report := range memberless {
+		log.Printf("line %d: %s is owned only by teams without active members\n", report.LineNumber, report.Pattern)
+	}
+	if len(memberless) > 0 {
+		return fmt.Errorf("%d rule(s) are owned only by teams without active members", len(memberless))
+	}
+	return nil
+}
+
+// reportUngrantableOwners reports the owners that cannot be granted in an
+// issue or fixes them in a pull request, as requested by the flags.
+func reportUngrantableOwners(ctx context.Context, client *github.Client, ruleset codeowners.Ruleset, opts *codeownerizer.Options) error {
+	owners, err := codeownerizer.FindUngrantableOwners(ctx, client, org, ruleset, opts)
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if len(owner.Aliases) > 0 {
+			log.Printf("%s cannot be granted: %s. Edit %s in %s\n", owner.Owner, owner.Reason, strings.Join(owner.Aliases, ", "), aliasFile)
+			continue
+		}
+		log.Printf("%s cannot be granted: %s\n", owner.Owner, owner.Reason)
+	}
+
+	if ungrantableIssue {
+		if err := codeownerizer.SyncUngrantableIssue(ctx, client, org, repo, owners); err != nil {
+			return err
+		}
+	}
+
+	if fixUngrantable {
+		mapping := map[string]string{}
+		if ownerMappingFile != "" {
+			mapping, err = codeownerizer.ReadOwnerMappingFile(ownerMappingFile)
+			if err != nil {
+				return err
+			}
+		}
+		pull, err := codeownerizer.FixUngrantableOwners(ctx, client, org, repo, owners, mapping)
+		if err != nil {
+			return err
+		}
+		if pull != nil {
+			log.Printf("%s fixes the owners that cannot be granted\n", pull.GetHTMLURL())
+		}
+	}
+	return nil
+}
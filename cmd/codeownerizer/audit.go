@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer"
+)
+
+var (
+	auditLog     string
+	auditWebhook string
+	auditSyslog  bool
+)
+
+func registerAuditFlags(fs *flag.FlagSet) {
+	fs.StringVar(&auditLog, "audit-log", "", "Append an audit event for every permission change to this JSONL file")
+	fs.StringVar(&auditWebhook, "audit-webhook", "", "Post an audit event for every permission change to this URL")
+	fs.BoolVar(&auditSyslog, "audit-syslog", false, "Write an audit event for every permission change to syslog")
+}
+
+// newAuditor returns an auditor writing to the sinks given by the audit flags,
+// or nil when none is given. The returned function closes the sinks.
+func newAuditor(ctx context.Context, client *github.Client) (*codeownerizer.Auditor, func(), error) {
+	var sinks []codeownerizer.AuditSink
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			if err := c.Close(); err != nil {
+				log.Println(err.Error())
+			}
+		}
+	}
+
+	if auditLog != "" {
+		sink, err := codeownerizer.NewJSONLSink(auditLog)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+		closers = append(closers, sink)
+	}
+	if auditWebhook != "" {
+		sinks = append(sinks, codeownerizer.NewWebhookSink(auditWebhook))
+	}
+	if auditSyslog {
+		sink, err := codeownerizer.NewSyslogSink("codeownerizer")
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+		closers = append(closers, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, closeAll, nil
+	}
+
+	// Installation tokens of GitHub Apps are not a user. On GitHub Actions the
+	// user who triggered the workflow is recorded instead.
+	actor, err := codeownerizer.GetActor(ctx, client)
+	if err != nil {
+		actor = os.Getenv("GITHUB_ACTOR")
+	}
+	if actor == "" {
+		actor = "unknown"
+	}
+
+	return &codeownerizer.Auditor{
+		Actor:     actor,
+		TokenType: codeownerizer.TokenType(os.Getenv("GITHUB_TOKEN")),
+		Sinks:     sinks,
+	}, closeAll, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/hmarr/codeowners"
+)
+
+var (
+	coverageSource string
+	coverageRef    string
+	coverageFormat string
+)
+
+func runCoverage(args []string) error {
+	fs := newFlagSet("coverage")
+	fs.StringVar(&coverageSource, "source", "local", "Where to list files from: local or api")
+	fs.StringVar(&coverageRef, "ref", "", "Git ref to list files at when the source is api. Defaults to the default branch")
+	fs.StringVar(&coverageFormat, "format", "tree", "Output format: tree or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	setDefaultRepository()
+
+	var ruleset codeowners.Ruleset
+	var files []string
+	switch coverageSource {
+	case "local":
+		root := repositoryRoot()
+		file, err := codeownerizer.ReadCodeownersFile(root)
+		if err != nil {
+			return err
+		}
+		ruleset, err = file.Ruleset()
+		if err != nil {
+			return err
+		}
+		files, err = codeownerizer.ListLocalFiles(root)
+		if err != nil {
+			return err
+		}
+	case "api":
+		file, err := codeownerizer.FetchCodeownersFile(ctx, client, org, repo, coverageRef)
+		if err != nil {
+			return err
+		}
+		ruleset, err = file.Ruleset()
+		if err != nil {
+			return err
+		}
+		ref := coverageRef
+		if ref == "" {
+			ref = "HEAD"
+		}
+		files, err = codeownerizer.ListTreeFiles(ctx, client, org, repo, ref)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown source: %s", coverageSource)
+	}
+
+	opts, err := analysisOptions(client)
+	if err != nil {
+		return err
+	}
+	reports, err := codeownerizer.AnalyzeRules(ctx, client, org, repo, ruleset, opts)
+	if err != nil {
+		return err
+	}
+
+	report, err := codeownerizer.AnalyzeCoverage(ruleset, reports, files)
+	if err != nil {
+		return err
+	}
+
+	switch coverageFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "tree":
+		printCoverageTree(os.Stdout, report)
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", coverageFormat)
+	}
+}
+
+// printCoverageTree prints the directories with their file counts, and the
+// files that have no owner able to approve.
+func printCoverageTree(w io.Writer, report *codeownerizer.CoverageReport) {
+	children := make(map[string][]string)
+	directories := make(map[string]codeownerizer.DirectoryCoverage)
+	for _, d := range report.Directories {
+		directories[d.Path] = d
+		if d.Path != "." {
+			parent := path.Dir(d.Path)
+			children[parent] = append(children[parent], d.Path)
+		}
+	}
+	for _, f := range report.Files {
+		if f.Status != codeownerizer.CoverageOwned {
+			parent := path.Dir(f.Path)
+			children[parent] = append(children[parent], f.Path)
+		}
+	}
+	files := make(map[string]codeownerizer.FileCoverage)
+	for _, f := range report.Files {
+		files[f.Path] = f
+	}
+
+	var walk func(p string, prefix string)
+	walk = func(p string, prefix string) {
+		for i, child := range children[p] {
+			branch, indent := "├── ", "│   "
+			if i == len(children[p])-1 {
+				branch, indent = "└── ", "    "
+			}
+			if d, ok := directories[child]; ok {
+				fmt.Fprintf(w, "%s%s%s/ %s\n", prefix, branch, path.Base(child), directorySummary(d))
+				walk(child, prefix+indent)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s [%s]\n", prefix, branch, path.Base(child), files[child].Status)
+		}
+	}
+
+	root, ok := directories["."]
+	if !ok {
+		return
+	}
+	fmt.Fprintf(w, ". %s\n", directorySummary(root))
+	walk(".", "")
+}
+
+func directorySummary(d codeownerizer.DirectoryCoverage) string {
+	var counts []string
+	if d.Owned > 0 {
+		counts = append(counts, fmt.Sprintf("%d owned", d.Owned))
+	}
+	if d.Unowned > 0 {
+		counts = append(counts, fmt.Sprintf("%d unowned", d.Unowned))
+	}
+	if d.Unapprovable > 0 {
+		counts = append(counts, fmt.Sprintf("%d unapprovable", d.Unapprovable))
+	}
+	summary := "(" + strings.Join(counts, ", ") + ")"
+	switch {
+	case d.Owned == 0 && d.Unapprovable == 0:
+		summary += " [unowned]"
+	case d.Owned == 0 && d.Unowned == 0:
+		summary += " [unapprovable]"
+	}
+	return summary
+}
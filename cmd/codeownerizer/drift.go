@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/grezar/codeownerizer"
+)
+
+var (
+	driftRepos  string
+	driftFormat string
+	driftIssue  bool
+)
+
+func runDrift(args []string) error {
+	fs := newFlagSet("drift")
+	registerGrantFlags(fs)
+	fs.StringVar(&driftRepos, "repos", "", "Comma-separated repositories to scan, as owner/name or as names in -org. Defaults to -org and -repo")
+	fs.StringVar(&driftFormat, "format", "markdown", "Output format: markdown or json")
+	fs.BoolVar(&driftIssue, "issue", false, "Open or update a tracking issue in each drifted repository, and close it once the drift is gone")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	if driftFormat != "markdown" && driftFormat != "json" {
+		return fmt.Errorf("unknown format: %s", driftFormat)
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	setDefaultRepository()
+
+	opts, err := grantOptions(client)
+	if err != nil {
+		return err
+	}
+
+	reports := []*codeownerizer.DriftReport{}
+	failed, drifted := 0, 0
+	for _, fullName := range driftRepositories() {
+		owner, name, _ := strings.Cut(fullName, "/")
+		report, err := codeownerizer.DetectDrift(ctx, client, owner, name, opts)
+		if err != nil {
+			log.Printf("failed to check %s: %s\n", fullName, err.Error())
+			failed++
+			continue
+		}
+		reports = append(reports, report)
+		if len(report.Owners) > 0 {
+			drifted++
+		}
+		if driftIssue {
+			if err := codeownerizer.SyncDriftIssue(ctx, client, owner, name, report); err != nil {
+				log.Printf("failed to update the tracking issue of %s: %s\n", fullName, err.Error())
+				failed++
+			}
+		}
+	}
+
+	switch driftFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	case "markdown":
+		fmt.Println("# CODEOWNERS access drift")
+		for _, report := range reports {
+			fmt.Println()
+			fmt.Print(report.Markdown())
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d repository check(s) failed", failed)
+	}
+	if drifted > 0 {
+		return fmt.Errorf("code owners lack access in %d repository(s)", drifted)
+	}
+	return nil
+}
+
+// driftRepositories lists the repositories to scan as owner/name.
+func driftRepositories() []string {
+	if driftRepos == "" {
+		return []string{org + "/" + repo}
+	}
+	var repositories []string
+	for _, r := range strings.Split(driftRepos, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if !strings.Contains(r, "/") {
+			r = org + "/" + r
+		}
+		repositories = append(repositories, r)
+	}
+	return repositories
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/gitea"
+)
+
+var (
+	giteaURL        string
+	giteaRef        string
+	giteaPermission string
+	giteaPlan       bool
+)
+
+func runGitea(args []string) error {
+	fs := flag.NewFlagSet("gitea", flag.ExitOnError)
+	fs.BoolVar(&version, "version", false, "Print version")
+	fs.StringVar(&giteaURL, "url", os.Getenv("GITEA_URL"), "URL of the Gitea or Forgejo instance")
+	fs.StringVar(&org, "org", "", "Owner of the repository")
+	fs.StringVar(&repo, "repo", "", "Name of the repository")
+	fs.StringVar(&giteaRef, "ref", "", "Git ref to read CODEOWNERS from. Defaults to the default branch")
+	fs.StringVar(&giteaPermission, "permission", "write", "Permission to grant to user owners: write or admin")
+	fs.BoolVar(&giteaPlan, "plan", false, "Show the grants without making them")
+	fs.StringVar(&ignoreFile, "ignore-file", ".codeownerizer-ignore", "File listing owners that are never granted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	if giteaURL == "" || org == "" || repo == "" {
+		return fmt.Errorf("-url, -org and -repo are required")
+	}
+	ignoredOwners, err := codeownerizer.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client := gitea.NewClient(giteaURL, os.Getenv("GITEA_TOKEN"), nil)
+
+	file, err := gitea.FetchCodeownersFile(ctx, client, org, repo, giteaRef)
+	if err != nil {
+		return err
+	}
+	actions, err := gitea.Plan(ctx, client, org, repo, file, &gitea.Options{
+		Permission:    giteaPermission,
+		IgnoredOwners: ignoredOwners,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("All code owners already have sufficient permissions.")
+		return nil
+	}
+	if giteaPlan {
+		printActions(os.Stdout, actions)
+		return nil
+	}
+	gitea.Apply(ctx, client, org, repo, actions)
+	return nil
+}
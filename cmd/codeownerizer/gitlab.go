@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/gitlab"
+)
+
+var (
+	gitlabURL         string
+	gitlabProject     string
+	gitlabRef         string
+	gitlabAccessLevel string
+	gitlabPlan        bool
+)
+
+func runGitLab(args []string) error {
+	fs := flag.NewFlagSet("gitlab", flag.ExitOnError)
+	fs.BoolVar(&version, "version", false, "Print version")
+	fs.StringVar(&gitlabURL, "url", envOr("CI_SERVER_URL", "https://gitlab.com"), "URL of the GitLab instance")
+	fs.StringVar(&gitlabProject, "project", os.Getenv("CI_PROJECT_PATH"), "Path of the GitLab project with its namespace")
+	fs.StringVar(&gitlabRef, "ref", "", "Git ref to read CODEOWNERS from. Defaults to the default branch")
+	fs.StringVar(&gitlabAccessLevel, "access-level", "developer", "Role to grant: developer, maintainer or owner")
+	fs.BoolVar(&gitlabPlan, "plan", false, "Show the grants without making them")
+	fs.StringVar(&ignoreFile, "ignore-file", ".codeownerizer-ignore", "File listing owners that are never granted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	if gitlabProject == "" {
+		return fmt.Errorf("-project is required")
+	}
+	level, err := gitlab.ParseAccessLevel(gitlabAccessLevel)
+	if err != nil {
+		return err
+	}
+	ignoredOwners, err := codeownerizer.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client := gitlab.NewClient(gitlabURL, os.Getenv("GITLAB_TOKEN"), nil)
+
+	file, err := gitlab.FetchCodeownersFile(ctx, client, gitlabProject, gitlabRef)
+	if err != nil {
+		return err
+	}
+	actions, err := gitlab.Plan(ctx, client, gitlabProject, file, &gitlab.Options{
+		AccessLevel:   level,
+		IgnoredOwners: ignoredOwners,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("All code owners already have sufficient permissions.")
+		return nil
+	}
+	if gitlabPlan {
+		printActions(os.Stdout, actions)
+		return nil
+	}
+	gitlab.Apply(ctx, client, gitlabProject, actions)
+	return nil
+}
+
+func envOr(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer"
+	"github.com/hmarr/codeowners"
+)
+
+// applyInteractively shows the planned grants and applies only those the user
+// approves, which it returns. Declined owners are appended to the ignore file
+// so that they are not proposed again.
+func applyInteractively(ctx context.Context, client *github.Client, ruleset codeowners.Ruleset, opts *codeownerizer.Options) ([]codeownerizer.Action, error) {
+	actions, err := codeownerizer.PlanRuleset(ctx, client, org, repo, ruleset, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		fmt.Println("All code owners already have sufficient permissions.")
+		return nil, nil
+	}
+
+	printActions(os.Stdout, actions)
+
+	in := bufio.NewReader(os.Stdin)
+	answer, err := prompt(in, fmt.Sprintf("Apply %d change(s)? [a]ll, [e]ach, [n]one: ", len(actions)), "a", "e", "n")
+	if err != nil {
+		return nil, err
+	}
+
+	var approved []codeownerizer.Action
+	var declined []string
+	for _, action := range actions {
+		switch answer {
+		case "a":
+			approved = append(approved, action)
+			continue
+		case "n":
+			declined = append(declined, action.Owner)
+			continue
+		}
+		yes, err := prompt(in, fmt.Sprintf("%s (%s)? [y]es, [n]o: ", action, action.Owner), "y", "n")
+		if err != nil {
+			return nil, err
+		}
+		if yes == "y" {
+			approved = append(approved, action)
+		} else {
+			declined = append(declined, action.Owner)
+		}
+	}
+
+	codeownerizer.ApplyActions(ctx, client, org, repo, approved, opts)
+
+	if len(declined) > 0 {
+		if err := codeownerizer.AppendIgnoreFile(ignoreFile, declined); err != nil {
+			return nil, err
+		}
+		log.Printf("%d declined owner(s) were added to %s.\n", len(declined), ignoreFile)
+		opts.IgnoredOwners = append(opts.IgnoredOwners, declined...)
+	}
+
+	return approved, nil
+}
+
+func printActions(w io.Writer, actions []codeownerizer.Action) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tOWNER\tCHANGE")
+	for i, action := range actions {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", i+1, action.Owner, action)
+	}
+	tw.Flush()
+}
+
+// prompt asks until one of the choices is answered.
+func prompt(in *bufio.Reader, question string, choices ...string) (string, error) {
+	for {
+		fmt.Print(question)
+		line, err := in.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		for _, choice := range choices {
+			if answer == choice {
+				return answer, nil
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v69/github"
 	"github.com/grezar/codeownerizer"
-	"github.com/hmarr/codeowners"
 	"golang.org/x/oauth2"
 )
 
@@ -19,9 +22,16 @@ var (
 	Version  string
 	Revision string
 
-	version bool
-	org     string
-	repo    string
+	version     bool
+	org         string
+	repo        string
+	accurate    bool
+	cache       string
+	cacheDir    string
+	cacheTTL    time.Duration
+	cacheMaxAge time.Duration
+	backend     string
+	aliasFile   string
 )
 
 func main() {
@@ -31,34 +41,144 @@ func main() {
 }
 
 func run() error {
-	flag.BoolVar(&version, "version", false, "Print version")
-	flag.StringVar(&org, "org", "", "GitHub organization")
-	flag.StringVar(&repo, "repo", "", "GitHub repository")
-	flag.Parse()
+	// Running without a command grants permissions to the code owners, which
+	// keeps the original invocation working on GitHub Actions.
+	command := "apply"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-	if version {
-		fmt.Println("codeownerizer", Version, Revision)
-		return nil
+	switch command {
+	case "apply":
+		return runApply(args)
+	case "plan":
+		return runPlan(args)
+	case "rules":
+		return runRules(args)
+	case "coverage":
+		return runCoverage(args)
+	case "permissions":
+		return runPermissions(args)
+	case "roles":
+		return runRoles(args)
+	case "normalize":
+		return runNormalize(args)
+	case "drift":
+		return runDrift(args)
+	case "serve":
+		return runServe(args)
+	case "gitlab":
+		return runGitLab(args)
+	case "gitea":
+		return runGitea(args)
+	default:
+		return fmt.Errorf("unknown command: %s", command)
 	}
+}
 
-	ctx := context.Background()
+// newFlagSet returns a flag set for the command with the flags shared by all
+// commands.
+func newFlagSet(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.BoolVar(&version, "version", false, "Print version")
+	fs.StringVar(&org, "org", "", "GitHub organization")
+	fs.StringVar(&repo, "repo", "", "GitHub repository")
+	fs.BoolVar(&accurate, "accurate", false, "Ask the permission-level endpoints for every owner instead of reading the permission lists")
+	fs.StringVar(&cache, "cache", "none", "Cache API responses: none, memory or file")
+	fs.StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory of the file cache")
+	fs.DurationVar(&cacheTTL, "cache-ttl", time.Hour, "How long user searches and organization teams are reused without asking GitHub")
+	fs.DurationVar(&cacheMaxAge, "cache-max-age", 7*24*time.Hour, "How long entries of the file cache are kept since they were last stored. 0 keeps them forever")
+	fs.StringVar(&backend, "backend", "rest", "API listing the teams and collaborators and resolving email owners: rest or graphql")
+	fs.StringVar(&aliasFile, "alias-file", ".codeownerizer-aliases.yaml", "YAML file mapping logical owners to the teams and users they stand for, shared by every repository of -repos-file")
+	return fs
+}
+
+func newClient(ctx context.Context) *github.Client {
+	if transport := newCachingTransport(); transport != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: transport})
+	}
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	return github.NewClient(tc)
+}
+
+// newCachingTransport returns the transport caching responses as requested by
+// the cache flags, or nil when caching is disabled.
+func newCachingTransport() http.RoundTripper {
+	var c codeownerizer.Cache
+	switch cache {
+	case "memory":
+		c = codeownerizer.NewMemoryCache()
+	case "file":
+		fileCache, err := codeownerizer.NewFileCache(cacheDir)
+		if err != nil {
+			log.Printf("running without a cache: %s\n", err.Error())
+			return nil
+		}
+		fileCache.MaxAge = cacheMaxAge
+		c = fileCache
+	case "none", "":
+		return nil
+	default:
+		log.Fatalf("unknown cache: %s", cache)
+	}
+	return &codeownerizer.CachingTransport{Cache: c, TTL: cacheTTL}
+}
+
+// newLister returns the lister of the API selected by the backend flag.
+func newLister(client *github.Client) codeownerizer.AccessLister {
+	switch backend {
+	case "graphql":
+		return codeownerizer.NewGraphQLLister(client, "")
+	case "rest", "":
+		return codeownerizer.NewRESTLister(client)
+	default:
+		log.Fatalf("unknown backend: %s", backend)
+		return nil
+	}
+}
 
-	ruleset, err := codeowners.LoadFileFromStandardLocation()
+// analysisOptions builds the options of the commands that check the owners
+// without granting them.
+func analysisOptions(client *github.Client) (*codeownerizer.Options, error) {
+	aliases, err := codeownerizer.ReadAliasFile(aliasFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &codeownerizer.Options{
+		Accurate: accurate,
+		Lister:   newLister(client),
+		Aliases:  aliases,
+	}, nil
+}
 
-	var owners []codeowners.Owner
-	for _, rule := range ruleset {
-		owners = append(owners, rule.Owners...)
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".codeownerizer-cache"
 	}
+	return filepath.Join(dir, "codeownerizer")
+}
 
-	// Default values when it runs on GitHub Actions
+// repositoryRoot returns the root of the git repository in the working
+// directory, or the working directory outside of one.
+func repositoryRoot() string {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "."
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func printVersion() {
+	fmt.Println("codeownerizer", Version, Revision)
+}
+
+// setDefaultRepository fills org and repo when it runs on GitHub Actions.
+func setDefaultRepository() {
 	if (os.Getenv("CI") == "true") && (os.Getenv("GITHUB_ACTION") != "") {
 		// GITHUB_REPOSITORY is the owner and repository name. For example, octocat/Hello-World.
 		githubRepository := strings.Split(os.Getenv("GITHUB_REPOSITORY"), "/")
@@ -71,6 +191,4 @@ func run() error {
 			repo = githubRepository[1]
 		}
 	}
-
-	return codeownerizer.AddUngrantedOwners(ctx, client, org, repo, owners)
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer"
+)
+
+// applyManifest reconciles the repositories listed in the repos file, or only
+// verifies them with -verify-only, and fails when any of them failed. A
+// repository that has not converged only fails with -verify-only or
+// -fail-on-unconverged.
+func applyManifest(ctx context.Context, client *github.Client, auditor *codeownerizer.Auditor) error {
+	if interactive {
+		return fmt.Errorf("a repos file cannot be applied interactively")
+	}
+	if unsupported := postApplyFlags(); len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be used with a repos file", strings.Join(unsupported, ", "))
+	}
+
+	setDefaultRepository()
+
+	manifest, err := codeownerizer.ReadRepoManifest(reposFile, org)
+	if err != nil {
+		return err
+	}
+
+	opts, err := grantOptions(client)
+	if err != nil {
+		return err
+	}
+	opts.Auditor = auditor
+
+	var results []codeownerizer.RepoResult
+	if verifyOnly {
+		results = codeownerizer.VerifyManifest(ctx, client, manifest, opts)
+	} else {
+		results = codeownerizer.ReconcileManifest(ctx, client, manifest, opts)
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tSTATUS\tGRANTS\tREMAINING\tPENDING\tERROR")
+	for _, result := range results {
+		status, remaining, pending, message := "ok", 0, 0, ""
+		if result.Convergence != nil {
+			remaining, pending = len(result.Convergence.Remaining), len(result.Convergence.Pending)
+		}
+		switch {
+		case result.Err != nil:
+			status, message = "error", result.Err.Error()
+		case result.Failed():
+			status = "not converged"
+		}
+		if result.Err != nil || (result.Failed() && (verifyOnly || failOnUnconverged)) {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n", result.Repo, status, len(result.Actions), remaining, pending, message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repositories failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/grezar/codeownerizer"
+)
+
+var normalizePR bool
+
+func runNormalize(args []string) error {
+	fs := newFlagSet("normalize")
+	fs.BoolVar(&normalizePR, "pr", false, "Open or update a pull request normalizing the CODEOWNERS file on the default branch instead of printing a diff of the local one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	if normalizePR {
+		setDefaultRepository()
+		pull, normalizations, err := codeownerizer.NormalizeEmailOwnersPullRequest(ctx, client, org, repo)
+		if err != nil {
+			return err
+		}
+		logNormalizations(normalizations)
+		if pull != nil {
+			log.Printf("%s normalizes the email owners\n", pull.GetHTMLURL())
+		}
+		return nil
+	}
+
+	file, err := codeownerizer.ReadCodeownersFile(repositoryRoot())
+	if err != nil {
+		return err
+	}
+	content, normalizations, err := codeownerizer.NormalizeEmailOwners(ctx, client, file.Content)
+	if err != nil {
+		return err
+	}
+	logNormalizations(normalizations)
+	fmt.Print(lineDiff(filepath.ToSlash(file.Path), file.Content, content))
+	return nil
+}
+
+func logNormalizations(normalizations []codeownerizer.EmailNormalization) {
+	for _, n := range normalizations {
+		if n.Username == "" {
+			log.Printf("%s is left as it is: it %s\n", n.Email, n.Reason)
+		}
+	}
+}
+
+// lineDiff prints a unified diff of contents that have the same number of
+// lines, which holds for rewritten owners.
+func lineDiff(path string, before []byte, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+	beforeLines := bytes.SplitAfter(before, []byte("\n"))
+	afterLines := bytes.SplitAfter(after, []byte("\n"))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	for i := range beforeLines {
+		if i >= len(afterLines) || bytes.Equal(beforeLines[i], afterLines[i]) {
+			continue
+		}
+		fmt.Fprintf(&b, "@@ -%d +%d @@\n", i+1, i+1)
+		writeDiffLine(&b, '-', beforeLines[i])
+		writeDiffLine(&b, '+', afterLines[i])
+	}
+	return b.String()
+}
+
+func writeDiffLine(b *bytes.Buffer, prefix byte, line []byte) {
+	b.WriteByte(prefix)
+	b.Write(line)
+	if !bytes.HasSuffix(line, []byte("\n")) {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/hmarr/codeowners"
+)
+
+var permissionsFormat string
+
+func runPermissions(args []string) error {
+	fs := newFlagSet("permissions")
+	fs.StringVar(&permissionsFormat, "format", "text", "Output format: text or json")
+	fs.StringVar(&ignoreFile, "ignore-file", ".codeownerizer-ignore", "File listing owners that are never granted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	ruleset, err := codeowners.LoadFileFromStandardLocation()
+	if err != nil {
+		return err
+	}
+
+	var owners []codeowners.Owner
+	for _, rule := range ruleset {
+		owners = append(owners, rule.Owners...)
+	}
+
+	setDefaultRepository()
+
+	opts, err := analysisOptions(client)
+	if err != nil {
+		return err
+	}
+	opts.IgnoredOwners, err = codeownerizer.ReadIgnoreFile(ignoreFile)
+	if err != nil {
+		return err
+	}
+
+	comparisons, err := codeownerizer.ComparePermissions(ctx, client, org, repo, owners, opts)
+	if err != nil {
+		return err
+	}
+
+	switch permissionsFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(comparisons); err != nil {
+			return err
+		}
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "OWNER\tFAST\tACCURATE\tROLE\t")
+		for _, c := range comparisons {
+			mismatch := ""
+			if c.Mismatch() {
+				mismatch = "MISMATCH"
+			} else if c.Error != "" {
+				mismatch = "ERROR: " + c.Error
+			}
+			fmt.Fprintf(w, "%s\t%t\t%t\t%s\t%s\n", c.Owner, c.Fast, c.Accurate, c.RoleName, mismatch)
+		}
+		w.Flush()
+	default:
+		return fmt.Errorf("unknown format: %s", permissionsFormat)
+	}
+
+	var mismatches, failures int
+	for _, c := range comparisons {
+		if c.Mismatch() {
+			mismatches++
+		}
+		if c.Error != "" {
+			failures++
+		}
+	}
+	var errs []error
+	if mismatches > 0 {
+		errs = append(errs, fmt.Errorf("%d owner(s) have different permissions in the fast and the accurate mode", mismatches))
+	}
+	if failures > 0 {
+		errs = append(errs, fmt.Errorf("%d owner(s) could not be compared", failures))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer"
+)
+
+var planOut string
+
+func runPlan(args []string) error {
+	fs := newFlagSet("plan")
+	registerGrantFlags(fs)
+	fs.StringVar(&planOut, "out", "", "Write the plan to this file so that it can be applied with `codeownerizer apply <file>`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	file, err := codeownerizer.ReadCodeownersFile(repositoryRoot())
+	if err != nil {
+		return err
+	}
+
+	setDefaultRepository()
+
+	opts, err := grantOptions(client)
+	if err != nil {
+		return err
+	}
+
+	plan, err := codeownerizer.NewPlan(ctx, client, org, repo, file, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Actions) == 0 {
+		fmt.Println("All code owners already have sufficient permissions.")
+	} else {
+		printActions(os.Stdout, plan.Actions)
+	}
+
+	if planOut != "" {
+		if err := codeownerizer.WritePlanFile(planOut, plan); err != nil {
+			return err
+		}
+		fmt.Printf("The plan was saved to %s.\n", planOut)
+	}
+	return nil
+}
+
+// applyPlanFile applies a saved plan, refusing it when the CODEOWNERS file or
+// the repository state has changed since it was made.
+func applyPlanFile(ctx context.Context, client *github.Client, path string, opts *codeownerizer.Options) error {
+	if interactive {
+		return fmt.Errorf("a saved plan cannot be applied interactively")
+	}
+	unsupported := postApplyFlags()
+	if verifyOnly {
+		unsupported = append(unsupported, "-verify-only")
+	}
+	if failOnUnconverged {
+		unsupported = append(unsupported, "-fail-on-unconverged")
+	}
+	if reposFile != "" {
+		unsupported = append(unsupported, "-repos-file")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be used with a saved plan", strings.Join(unsupported, ", "))
+	}
+
+	plan, err := codeownerizer.ReadPlanFile(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := codeownerizer.ReadCodeownersFile(repositoryRoot())
+	if err != nil {
+		return err
+	}
+
+	return codeownerizer.ApplyPlan(ctx, client, plan, file, opts)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/grezar/codeownerizer"
+)
+
+func runRoles(args []string) error {
+	fs := newFlagSet("roles")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	setDefaultRepository()
+
+	roles, err := codeownerizer.ListCustomRepoRoles(ctx, client, org)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tBASE ROLE\tCAN APPROVE")
+	for _, role := range roles {
+		fmt.Fprintf(w, "%s\t%s\t%t\n", role.GetName(), role.GetBaseRole(), codeownerizer.CanApprove(role))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/hmarr/codeowners"
+)
+
+var rulesFormat string
+
+func runRules(args []string) error {
+	fs := newFlagSet("rules")
+	fs.StringVar(&rulesFormat, "format", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	ctx := context.Background()
+	client := newClient(ctx)
+
+	ruleset, err := codeowners.LoadFileFromStandardLocation()
+	if err != nil {
+		return err
+	}
+
+	setDefaultRepository()
+
+	opts, err := analysisOptions(client)
+	if err != nil {
+		return err
+	}
+	reports, err := codeownerizer.AnalyzeRules(ctx, client, org, repo, ruleset, opts)
+	if err != nil {
+		return err
+	}
+
+	switch rulesFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	case "text":
+		printRuleReports(reports)
+	default:
+		return fmt.Errorf("unknown format: %s", rulesFormat)
+	}
+
+	if blocked := codeownerizer.BlockedRules(reports); len(blocked) > 0 {
+		return fmt.Errorf("%d rule(s) cannot be approved by any of their owners", len(blocked))
+	}
+	return nil
+}
+
+func printRuleReports(reports []codeownerizer.RuleReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tPATTERN\tSTATUS\tOWNERS")
+	for _, report := range reports {
+		status := "ok"
+		if report.Blocked {
+			status = "BLOCKED"
+		}
+		var owners []string
+		for _, owner := range report.Owners {
+			if owner.CanApprove {
+				owners = append(owners, owner.Owner)
+			} else {
+				owners = append(owners, fmt.Sprintf("%s (%s)", owner.Owner, owner.Reason))
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", report.LineNumber, report.Pattern, status, strings.Join(owners, ", "))
+	}
+	w.Flush()
+}
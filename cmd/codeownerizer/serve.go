@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/grezar/codeownerizer"
+)
+
+var serveAddr string
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	registerGrantFlags(fs)
+	registerAuditFlags(fs)
+	fs.StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if version {
+		printVersion()
+		return nil
+	}
+
+	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if secret == "" {
+		return fmt.Errorf("GITHUB_WEBHOOK_SECRET is required to verify webhooks")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newClient(ctx)
+
+	auditor, closeAuditor, err := newAuditor(ctx, client)
+	if err != nil {
+		return err
+	}
+	defer closeAuditor()
+
+	opts, err := grantOptions(client)
+	if err != nil {
+		return err
+	}
+	opts.Auditor = auditor
+
+	owners := codeownerizer.NewOwnerCache()
+	server := codeownerizer.NewServer([]byte(secret), codeownerizer.NewRepositoryReconciler(client, opts, owners))
+	server.OnTeamRenamed = codeownerizer.NewStaleTeamReporter(client)
+	server.Owners = owners
+	go server.Run(ctx)
+
+	httpServer := &http.Server{
+		Addr:              serveAddr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Println(err.Error())
+		}
+	}()
+
+	log.Printf("listening on %s\n", serveAddr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
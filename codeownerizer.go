@@ -3,9 +3,9 @@ package codeownerizer
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"log"
+	"strings"
 
 	"github.com/google/go-github/v69/github"
 	"github.com/hmarr/codeowners"
@@ -13,97 +13,161 @@ import (
 
 const pushPermission = "push"
 
-func AddUngrantedOwners(ctx context.Context, api *github.Client, org string, repo string, owners []codeowners.Owner) error {
-	owners = uniqueOwners(owners)
+// Options configures how AddUngrantedOwners grants permissions to code owners.
+// A nil *Options is treated as the zero value.
+type Options struct {
+	// PreferTeamAccess treats a user owner as sufficient when the user belongs
+	// to a team that already has the push permission on the repository.
+	PreferTeamAccess bool
+
+	// CodeownersTeam is the slug of a team that user owners lacking the push
+	// permission are added to instead of being added as direct collaborators.
+	CodeownersTeam string
+
+	// Permission is the permission granted to code owners. It is either a base
+	// permission including push, or the name of a custom repository role based
+	// on write or higher. Defaults to "push".
+	Permission string
+
+	// Accurate asks the permission-level endpoints for every owner instead of
+	// reading the Permissions maps of the repository teams and collaborators,
+	// which do not reflect custom repository roles.
+	Accurate bool
+
+	// Auditor records every permission change. It is optional.
+	Auditor *Auditor
+
+	// IgnoredOwners are owners, as written in CODEOWNERS, that are never
+	// granted, for example because an admin declined them before.
+	IgnoredOwners []string
+
+	// Backend manages the access to the repository. Defaults to the REST API
+	// through the client passed along with the options.
+	Backend Backend
+
+	// Lister lists the teams and collaborators of the repository and resolves
+	// email owners in place of the Backend. It is optional.
+	Lister AccessLister
+
+	// Aliases are logical owners used in CODEOWNERS that stand for real teams
+	// and users. Ignoring an alias ignores the owners it stands for.
+	Aliases Aliases
+}
+
+// expandRuleset replaces the aliases among the owners of the rules.
+func (o *Options) expandRuleset(ruleset codeowners.Ruleset) (codeowners.Ruleset, error) {
+	return o.Aliases.expandRuleset(ruleset, o.isIgnored)
+}
+
+func (o *Options) permission() string {
+	if o.Permission == "" {
+		return pushPermission
+	}
+	return o.Permission
+}
+
+func (o *Options) backend(api *github.Client) Backend {
+	if o.Backend == nil {
+		return NewRESTBackend(api)
+	}
+	return o.Backend
+}
+
+func (o *Options) lister(api *github.Client) AccessLister {
+	if o.Lister == nil {
+		return o.backend(api)
+	}
+	return o.Lister
+}
+
+// isIgnored reports whether the owner is ignored. Owners are compared
+// case-insensitively, as GitHub does.
+func (o *Options) isIgnored(owner string) bool {
+	for _, ignored := range o.IgnoredOwners {
+		if strings.EqualFold(ignored, owner) {
+			return true
+		}
+	}
+	return false
+}
 
-	teams, err := ListTeams(ctx, api, org, repo)
+// AddUngrantedOwners grants the permission to the code owners who lack it.
+// Failing to grant an owner is logged and does not stop the others from being
+// granted.
+func AddUngrantedOwners(ctx context.Context, api *github.Client, org string, repo string, owners []codeowners.Owner, opts *Options) error {
+	actions, err := PlanGrants(ctx, api, org, repo, owners, opts)
 	if err != nil {
 		return err
 	}
+	ApplyActions(ctx, api, org, repo, actions, opts)
+	return nil
+}
 
-	collaborators, err := ListCollaborators(ctx, api, org, repo)
+// Reconcile grants the permission to the owners of the rules who lack it, like
+// AddUngrantedOwners, and records which rules each grant was made for.
+func Reconcile(ctx context.Context, api *github.Client, org string, repo string, ruleset codeowners.Ruleset, opts *Options) error {
+	actions, err := PlanRuleset(ctx, api, org, repo, ruleset, opts)
 	if err != nil {
 		return err
 	}
+	ApplyActions(ctx, api, org, repo, actions, opts)
+	return nil
+}
 
-	for _, owner := range owners {
-		switch owner.Type {
-		case codeowners.TeamOwner:
-			teamOwnerName := strings.Split(owner.String(), "/")[1]
-
-			// Grant a push permission to
-			// - a team that is already have an access to the repository but does not have a push permission.
-			// - a team that does not have an access to the repository.
-			if !hasTeamOwnerSufficientPermission(teams, teamOwnerName) || !containsTeamOwner(teams, teamOwnerName) {
-				resp, err := api.Teams.AddTeamRepoBySlug(ctx, org, teamOwnerName, org, repo, &github.TeamAddTeamRepoOptions{
-					Permission: pushPermission,
-				})
-				if err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				if err = github.CheckResponse(resp.Response); err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				log.Printf("%s was added to the repo with the %s permission.\n", owner.String(), pushPermission)
-			}
-		case codeowners.UsernameOwner:
-			userOwnerName := strings.TrimPrefix(owner.String(), "@")
-
-			if !hasUserOwnerSufficientPermission(collaborators, userOwnerName) || !containsUserOwner(collaborators, userOwnerName) {
-				_, resp, err := api.Repositories.AddCollaborator(ctx, org, repo, userOwnerName, &github.RepositoryAddCollaboratorOptions{
-					Permission: pushPermission,
-				})
-				if err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				if err = github.CheckResponse(resp.Response); err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				log.Printf("%s was added to the repo with the %s permission.\n", owner.String(), pushPermission)
-			}
-		case codeowners.EmailOwner:
-			emailOwnerEmail := owner.String()
-			userSearchResult, resp, err := api.Search.Users(ctx, fmt.Sprintf("%s in:email", emailOwnerEmail), nil)
-			if err != nil {
-				log.Println(err.Error())
-				continue
-			}
-			if err = github.CheckResponse(resp.Response); err != nil {
-				log.Println(err.Error())
-				continue
-			}
-			if len(userSearchResult.Users) > 1 {
-				log.Printf("multiple users who has %s in email was found\n", emailOwnerEmail)
-				continue
-			}
-
-			emailOwnerUsername := stringify(userSearchResult.Users[0].Login)
-
-			if !hasUserOwnerSufficientPermission(collaborators, emailOwnerUsername) || !containsUserOwner(collaborators, emailOwnerUsername) {
-				_, resp, err := api.Repositories.AddCollaborator(ctx, org, repo, emailOwnerUsername, &github.RepositoryAddCollaboratorOptions{
-					Permission: pushPermission,
-				})
-				if err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				if err = github.CheckResponse(resp.Response); err != nil {
-					log.Println(err.Error())
-					continue
-				}
-				log.Printf("%s was added to the repo with the %s permission.\n", owner.String(), pushPermission)
-			}
-		default:
-			log.Printf("unknown owner type: %s\n", owner.Type)
-			continue
-		}
+func logAppliedAction(action Action) {
+	switch action.Type {
+	case ActionAddTeamMember:
+		log.Printf("%s was added to the %s team.\n", action.Owner, action.Team)
+	default:
+		log.Printf("%s was added to the repo with the %s permission.\n", action.Owner, action.Permission)
 	}
+}
 
-	return nil
+// resolveEmailOwner finds the login of the only user who has the email.
+func resolveEmailOwner(ctx context.Context, api *github.Client, email string) (string, error) {
+	logins, err := searchEmailLogins(ctx, api, email)
+	if err != nil {
+		return "", err
+	}
+	return onlyLogin(email, logins)
+}
+
+// searchEmailLogins finds the logins of the users who have the email.
+func searchEmailLogins(ctx context.Context, api *github.Client, email string) ([]string, error) {
+	userSearchResult, resp, err := api.Search.Users(ctx, fmt.Sprintf("%s in:email", email), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = github.CheckResponse(resp.Response); err != nil {
+		return nil, err
+	}
+	logins := []string{}
+	for _, user := range userSearchResult.Users {
+		logins = append(logins, stringify(user.Login))
+	}
+	return logins, nil
+}
+
+func onlyLogin(email string, logins []string) (string, error) {
+	if len(logins) != 1 {
+		return "", &EmailResolutionError{Email: email, Matches: len(logins)}
+	}
+	return logins[0], nil
+}
+
+// EmailResolutionError is returned when an email owner does not resolve to
+// exactly one user.
+type EmailResolutionError struct {
+	Email string
+	// Matches is the number of users who have the email.
+	Matches int
+}
+
+func (e *EmailResolutionError) Error() string {
+	if e.Matches == 0 {
+		return fmt.Sprintf("no user who has %s in email was found", e.Email)
+	}
+	return fmt.Sprintf("multiple users who has %s in email was found", e.Email)
 }
 
 func ListTeams(ctx context.Context, api *github.Client, org string, repo string) ([]*github.Team, error) {
@@ -123,6 +187,59 @@ func ListTeams(ctx context.Context, api *github.Client, org string, repo string)
 	return allTeams, nil
 }
 
+func ListChildTeams(ctx context.Context, api *github.Client, org string, slug string) ([]*github.Team, error) {
+	allTeams := []*github.Team{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		teams, resp, err := api.Teams.ListChildTeamsByParentSlug(ctx, org, slug, opts)
+		if err != nil {
+			return nil, err
+		}
+		allTeams = append(allTeams, teams...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allTeams, nil
+}
+
+func ListTeamRepos(ctx context.Context, api *github.Client, org string, slug string) ([]*github.Repository, error) {
+	allRepos := []*github.Repository{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := api.Teams.ListTeamReposBySlug(ctx, org, slug, opts)
+		if err != nil {
+			return nil, err
+		}
+		allRepos = append(allRepos, repos...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allRepos, nil
+}
+
+func ListTeamMembers(ctx context.Context, api *github.Client, org string, slug string) ([]*github.User, error) {
+	allMembers := []*github.User{}
+	opts := &github.TeamListTeamMembersOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		members, resp, err := api.Teams.ListTeamMembersBySlug(ctx, org, slug, opts)
+		if err != nil {
+			return nil, err
+		}
+		allMembers = append(allMembers, members...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allMembers, nil
+}
+
 func ListCollaborators(ctx context.Context, api *github.Client, org string, repo string) ([]*github.User, error) {
 	allCollaborators := []*github.User{}
 	opts := &github.ListCollaboratorsOptions{
@@ -141,3 +258,22 @@ func ListCollaborators(ctx context.Context, api *github.Client, org string, repo
 	}
 	return allCollaborators, nil
 }
+
+// ListInvitations lists the pending invitations to collaborate on the
+// repository.
+func ListInvitations(ctx context.Context, api *github.Client, org string, repo string) ([]*github.RepositoryInvitation, error) {
+	allInvitations := []*github.RepositoryInvitation{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		invitations, resp, err := api.Repositories.ListInvitations(ctx, org, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		allInvitations = append(allInvitations, invitations...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return allInvitations, nil
+}
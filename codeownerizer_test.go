@@ -2,8 +2,11 @@ package codeownerizer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -98,7 +101,7 @@ func TestAddUngrantedOwnersWithTeamOwner(t *testing.T) {
 
 	client := github.NewClient(mockedHTTPClient)
 	ctx := context.Background()
-	err = AddUngrantedOwners(ctx, client, "org", "repo", owners)
+	err = AddUngrantedOwners(ctx, client, "org", "repo", owners, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -208,7 +211,7 @@ func TestAddUngrantedOwnersWithUserOwner(t *testing.T) {
 
 	client := github.NewClient(mockedHTTPClient)
 	ctx := context.Background()
-	err = AddUngrantedOwners(ctx, client, "org", "repo", owners)
+	err = AddUngrantedOwners(ctx, client, "org", "repo", owners, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -342,7 +345,7 @@ func TestAddUngrantedOwnersWithEmailOwner(t *testing.T) {
 
 	client := github.NewClient(mockedHTTPClient)
 	ctx := context.Background()
-	err = AddUngrantedOwners(ctx, client, "org", "repo", owners)
+	err = AddUngrantedOwners(ctx, client, "org", "repo", owners, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -517,7 +520,7 @@ func TestAddUngrantedOwners(t *testing.T) {
 	)
 	client := github.NewClient(mockedHTTPClient)
 
-	err = AddUngrantedOwners(ctx, client, "org", "repo", owners)
+	err = AddUngrantedOwners(ctx, client, "org", "repo", owners, nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -562,3 +565,413 @@ func TestAddUngrantedOwners(t *testing.T) {
 		)
 	}
 }
+
+func TestAddUngrantedOwnersWithCodeownersTeam(t *testing.T) {
+	org := "org"
+	repo := "repo"
+
+	ruleset, err := codeowners.LoadFile("testdata/CODEOWNERS-USER")
+	if err != nil {
+		t.Error(err)
+	}
+	var owners []codeowners.Owner
+	for _, rule := range ruleset {
+		owners = append(owners, rule.Owners...)
+	}
+
+	var addedToCodeownersTeam []string
+	putOrgsTeamsMembershipsByOrgByTeamSlugByUsername := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/orgs/%s/teams/%s/memberships/{username}", org, "codeowners"),
+		Method:  "PUT",
+	}
+	collaboratorAdded := false
+	putReposCollaboratorsByOwnerByRepoByUsername := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/repos/%s/%s/collaborators/{username}", org, repo),
+		Method:  "PUT",
+	}
+	codeownersTeamAddedToRepo := false
+	putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithCodeownersTeam := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/orgs/%s/teams/%s/repos/%s/%s", org, "codeowners", org, repo),
+		Method:  "PUT",
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				// The codeowners team already has push permission.
+				{
+					Name: github.Ptr("Codeowners"),
+					Slug: github.Ptr("codeowners"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+				{
+					Name: github.Ptr("Octocats"),
+					Slug: github.Ptr("octocats"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				// Code owner has push permission as a collaborator.
+				{
+					Login: github.Ptr("octocat"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsTeamsMembershipsByOrgByTeamSlugByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Only doctocat belongs to the octocats team.
+				if r.URL.Path != fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", org, "octocats", "doctocat") {
+					mock.WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				_, _ = w.Write(mock.MustMarshal(github.Membership{
+					State: github.Ptr("active"),
+				}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putOrgsTeamsMembershipsByOrgByTeamSlugByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				addedToCodeownersTeam = append(addedToCodeownersTeam, path.Base(r.URL.Path))
+				_, _ = w.Write(mock.MustMarshal(github.Membership{
+					State: github.Ptr("active"),
+				}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putReposCollaboratorsByOwnerByRepoByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				collaboratorAdded = true
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithCodeownersTeam,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				codeownersTeamAddedToRepo = true
+			}),
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+	err = AddUngrantedOwners(ctx, client, org, repo, owners, &Options{
+		PreferTeamAccess: true,
+		CodeownersTeam:   "codeowners",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// octocat has push permission directly and doctocat through the octocats team.
+	if diff := cmp.Diff([]string{"octocat2"}, addedToCodeownersTeam); diff != "" {
+		t.Errorf("unexpected users added to the codeowners team\n%s", diff)
+	}
+	if collaboratorAdded {
+		t.Errorf(
+			"expected %s %s not to be called\n",
+			putReposCollaboratorsByOwnerByRepoByUsername.Method,
+			putReposCollaboratorsByOwnerByRepoByUsername.Pattern,
+		)
+	}
+	if codeownersTeamAddedToRepo {
+		t.Errorf(
+			"expected %s %s not to be called\n",
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithCodeownersTeam.Method,
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithCodeownersTeam.Pattern,
+		)
+	}
+}
+
+func TestAddUngrantedOwnersWithChildTeamOwner(t *testing.T) {
+	org := "org"
+	repo := "repo"
+
+	owners := []codeowners.Owner{
+		{Value: "octo-org/child", Type: codeowners.TeamOwner},
+		{Value: "octo-org/grandchild", Type: codeowners.TeamOwner},
+		{Value: "octo-org/other", Type: codeowners.TeamOwner},
+	}
+
+	var addedToRepo []string
+	putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/orgs/%s/teams/{team_slug}/repos/%s/%s", org, org, repo),
+		Method:  "PUT",
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				// Child teams inherit the push permission from this team.
+				{
+					Name: github.Ptr("parent"),
+					Slug: github.Ptr("parent"),
+					Permissions: map[string]bool{
+						"pull": true,
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsTeamsTeamsByOrgByTeamSlug,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				children := []github.Team{}
+				switch r.URL.Path {
+				case fmt.Sprintf("/orgs/%s/teams/%s/teams", org, "parent"):
+					children = append(children, github.Team{Slug: github.Ptr("child")})
+				case fmt.Sprintf("/orgs/%s/teams/%s/teams", org, "child"):
+					children = append(children, github.Team{Slug: github.Ptr("grandchild")})
+				}
+				_, _ = w.Write(mock.MustMarshal(children))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				addedToRepo = append(addedToRepo, strings.Split(r.URL.Path, "/")[4])
+			}),
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+	err := AddUngrantedOwners(ctx, client, org, repo, owners, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if diff := cmp.Diff([]string{"other"}, addedToRepo); diff != "" {
+		t.Errorf("unexpected teams added to the repo\n%s", diff)
+	}
+}
+
+func TestAddUngrantedOwnersWithAccurateMode(t *testing.T) {
+	org := "org"
+	repo := "repo"
+
+	owners := []codeowners.Owner{
+		{Value: "octo-org/octocats", Type: codeowners.TeamOwner},
+		{Value: "octocat", Type: codeowners.UsernameOwner},
+		{Value: "doctocat", Type: codeowners.UsernameOwner},
+	}
+
+	var teamsAddedToRepo []string
+	putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/orgs/%s/teams/{team_slug}/repos/%s/%s", org, org, repo),
+		Method:  "PUT",
+	}
+	var usersAddedToRepo []string
+	putReposCollaboratorsByOwnerByRepoByUsername := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/repos/%s/%s/collaborators/{username}", org, repo),
+		Method:  "PUT",
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		// The permission lists are stale, so they must not be used.
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				{
+					Slug: github.Ptr("octocats"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				{
+					Login: github.Ptr("octocat"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo,
+			github.Repository{
+				RoleName: github.Ptr("triage"),
+				Permissions: map[string]bool{
+					"pull":   true,
+					"triage": true,
+				},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCollaboratorsPermissionByOwnerByRepoByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				level := github.RepositoryPermissionLevel{
+					Permission: github.Ptr("read"),
+					RoleName:   github.Ptr("read"),
+				}
+				// doctocat can push through a custom role based on write.
+				if strings.Contains(r.URL.Path, "/doctocat/") {
+					level = github.RepositoryPermissionLevel{
+						Permission: github.Ptr("write"),
+						RoleName:   github.Ptr("code-reviewer"),
+					}
+				}
+				_, _ = w.Write(mock.MustMarshal(level))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				teamsAddedToRepo = append(teamsAddedToRepo, strings.Split(r.URL.Path, "/")[4])
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			putReposCollaboratorsByOwnerByRepoByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				usersAddedToRepo = append(usersAddedToRepo, path.Base(r.URL.Path))
+			}),
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+	err := AddUngrantedOwners(ctx, client, org, repo, owners, &Options{Accurate: true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if diff := cmp.Diff([]string{"octocats"}, teamsAddedToRepo); diff != "" {
+		t.Errorf("unexpected teams added to the repo\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"octocat"}, usersAddedToRepo); diff != "" {
+		t.Errorf("unexpected users added to the repo\n%s", diff)
+	}
+}
+
+func TestAddUngrantedOwnersWithCustomRole(t *testing.T) {
+	org := "org"
+	repo := "repo"
+
+	owners := []codeowners.Owner{
+		{Value: "octo-org/octocats", Type: codeowners.TeamOwner},
+		{Value: "octocat", Type: codeowners.UsernameOwner},
+		{Value: "doctocat", Type: codeowners.UsernameOwner},
+	}
+
+	grantedPermissions := map[string]string{}
+	putReposCollaboratorsByOwnerByRepoByUsername := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/repos/%s/%s/collaborators/{username}", org, repo),
+		Method:  "PUT",
+	}
+	octocatsTeamAddedToRepo := false
+	putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithOctocatsTeam := mock.EndpointPattern{
+		Pattern: fmt.Sprintf("/orgs/%s/teams/%s/repos/%s/%s", org, "octocats", org, repo),
+		Method:  "PUT",
+	}
+
+	newMockedHTTPClient := func() *http.Client {
+		return mock.NewMockedHTTPClient(
+			mock.WithRequestMatch(
+				mock.GetReposTeamsByOwnerByRepo,
+				[]github.Team{
+					// The Permissions map does not reflect the custom role.
+					{
+						Slug:       github.Ptr("octocats"),
+						Permission: github.Ptr("code-reviewer"),
+						Permissions: map[string]bool{
+							"pull": true,
+						},
+					},
+				},
+			),
+			mock.WithRequestMatch(
+				mock.GetReposCollaboratorsByOwnerByRepo,
+				[]github.User{
+					{
+						Login:    github.Ptr("octocat"),
+						RoleName: github.Ptr("code-reviewer"),
+						Permissions: map[string]bool{
+							"pull": true,
+						},
+					},
+					{
+						Login:    github.Ptr("doctocat"),
+						RoleName: github.Ptr("triager"),
+						Permissions: map[string]bool{
+							"pull":   true,
+							"triage": true,
+						},
+					},
+				},
+			),
+			mock.WithRequestMatch(
+				mock.GetOrgsCustomRepositoryRolesByOrg,
+				github.OrganizationCustomRepoRoles{
+					TotalCount: github.Ptr(2),
+					CustomRepoRoles: []*github.CustomRepoRoles{
+						{Name: github.Ptr("code-reviewer"), BaseRole: github.Ptr("write")},
+						{Name: github.Ptr("triager"), BaseRole: github.Ptr("triage")},
+					},
+				},
+			),
+			mock.WithRequestMatchHandler(
+				putReposCollaboratorsByOwnerByRepoByUsername,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					var opts github.RepositoryAddCollaboratorOptions
+					if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+						t.Error(err)
+					}
+					grantedPermissions[path.Base(r.URL.Path)] = opts.Permission
+				}),
+			),
+			mock.WithRequestMatchHandler(
+				putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithOctocatsTeam,
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					octocatsTeamAddedToRepo = true
+				}),
+			),
+		)
+	}
+
+	ctx := context.Background()
+	err := AddUngrantedOwners(ctx, github.NewClient(newMockedHTTPClient()), org, repo, owners, &Options{
+		Permission: "code-reviewer",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if diff := cmp.Diff(map[string]string{"doctocat": "code-reviewer"}, grantedPermissions); diff != "" {
+		t.Errorf("unexpected granted permissions\n%s", diff)
+	}
+	if octocatsTeamAddedToRepo {
+		t.Errorf(
+			"expected %s %s not to be called\n",
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithOctocatsTeam.Method,
+			putOrgsTeamsReposByOrgByTeamSlugByOwnerByRepoWithOctocatsTeam.Pattern,
+		)
+	}
+
+	// A custom role based on a role below write cannot be granted.
+	err = AddUngrantedOwners(ctx, github.NewClient(newMockedHTTPClient()), org, repo, owners, &Options{
+		Permission: "triager",
+	})
+	if err == nil {
+		t.Error("expected an error for the triager role")
+	}
+}
@@ -0,0 +1,341 @@
+// Package codeownersfile parses CODEOWNERS files into a syntax tree that can be
+// edited and written back. Unlike github.com/hmarr/codeowners, which only reads
+// rules, it keeps comments, blank lines and whitespace, so that serializing an
+// unmodified file reproduces it byte for byte and edits only touch the owners
+// they change.
+package codeownersfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+)
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Lines []*Line
+}
+
+// Line is a line of a CODEOWNERS file. A rule line has a pattern, while a
+// blank or comment-only line does not.
+type Line struct {
+	// Number is the 1-based line number in the parsed file.
+	Number int
+	// Indent is the whitespace before the pattern or the comment.
+	Indent string
+	// Pattern is the pattern as written, with backslash escapes.
+	Pattern string
+	Owners  []*Owner
+	// Trailing is the whitespace after the last owner or the pattern.
+	Trailing string
+	// Comment is the comment at the end of the line, starting with #.
+	Comment string
+	// EOL is the line ending, which is empty for the last line of a file
+	// without a trailing newline.
+	EOL string
+}
+
+// Owner is an owner of a rule line.
+type Owner struct {
+	// Space is the whitespace separating the owner from what precedes it.
+	Space string
+	Value string
+}
+
+// Parse reads a CODEOWNERS file. It never fails on the content, as every line
+// can be represented, and only returns errors from the reader.
+func Parse(r io.Reader) (*File, error) {
+	reader := bufio.NewReader(r)
+	file := &File{}
+	for number := 1; ; number++ {
+		text, err := reader.ReadString('\n')
+		if text != "" {
+			file.Lines = append(file.Lines, parseLine(number, text))
+		}
+		if err == io.EOF {
+			return file, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ParseBytes parses the content of a CODEOWNERS file.
+func ParseBytes(content []byte) *File {
+	file, _ := Parse(bytes.NewReader(content))
+	return file
+}
+
+func parseLine(number int, text string) *Line {
+	body := strings.TrimRight(text, "\r\n")
+	line := &Line{Number: number, EOL: text[len(body):]}
+
+	i := skipSpace(body, 0)
+	line.Indent = body[:i]
+	if i < len(body) && body[i] != '#' {
+		start := i
+		for i < len(body) && !isSpace(body[i]) {
+			if body[i] == '\\' && i+1 < len(body) {
+				i++
+			}
+			i++
+		}
+		line.Pattern = body[start:i]
+
+		for {
+			start := i
+			i = skipSpace(body, i)
+			if i == len(body) || body[i] == '#' {
+				i = start
+				break
+			}
+			end := i
+			for end < len(body) && !isSpace(body[end]) {
+				end++
+			}
+			line.Owners = append(line.Owners, &Owner{Space: body[start:i], Value: body[i:end]})
+			i = end
+		}
+	}
+
+	comment := skipSpace(body, i)
+	line.Trailing = body[i:comment]
+	line.Comment = body[comment:]
+	return line
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// IsRule reports whether the line is a rule rather than a blank or comment
+// line.
+func (l *Line) IsRule() bool {
+	return l.Pattern != ""
+}
+
+// UnescapedPattern returns the pattern with the backslash escapes removed.
+func (l *Line) UnescapedPattern() string {
+	var b strings.Builder
+	for i := 0; i < len(l.Pattern); i++ {
+		if l.Pattern[i] == '\\' && i+1 < len(l.Pattern) {
+			i++
+		}
+		b.WriteByte(l.Pattern[i])
+	}
+	return b.String()
+}
+
+// String returns the line as written, including the line ending.
+func (l *Line) String() string {
+	var b strings.Builder
+	b.WriteString(l.Indent)
+	b.WriteString(l.Pattern)
+	for _, owner := range l.Owners {
+		b.WriteString(owner.Space)
+		b.WriteString(owner.Value)
+	}
+	b.WriteString(l.Trailing)
+	b.WriteString(l.Comment)
+	b.WriteString(l.EOL)
+	return b.String()
+}
+
+// HasOwner reports whether the line lists the owner, compared
+// case-insensitively like GitHub does.
+func (l *Line) HasOwner(owner string) bool {
+	return l.ownerIndex(owner) >= 0
+}
+
+func (l *Line) ownerIndex(owner string) int {
+	for i, o := range l.Owners {
+		if strings.EqualFold(o.Value, owner) {
+			return i
+		}
+	}
+	return -1
+}
+
+// RemoveOwner removes the owner from the line and reports whether it was
+// listed. The whitespace after the pattern is kept, so aligned owners stay
+// aligned. Removing the last owner comments the rule out, see CommentOut.
+func (l *Line) RemoveOwner(owner string) bool {
+	i := l.ownerIndex(owner)
+	if i < 0 {
+		return false
+	}
+	if len(l.Owners) == 1 {
+		l.CommentOut()
+		return true
+	}
+	if i == 0 {
+		l.Owners[1].Space = l.Owners[0].Space
+	}
+	l.Owners = append(l.Owners[:i], l.Owners[i+1:]...)
+	return true
+}
+
+// CommentOut turns the rule into a comment noting that it has no owner left,
+// followed by the rule as written. A rule without owners would make the
+// matching files unowned, while the commented out rule lets the previous
+// rules apply and keeps the removed owners visible.
+func (l *Line) CommentOut() {
+	rule := strings.TrimPrefix(strings.TrimSuffix(l.String(), l.EOL), l.Indent)
+	l.Pattern = ""
+	l.Owners = nil
+	l.Trailing = ""
+	l.Comment = "# No owner left: " + rule
+}
+
+// ReplaceOwner replaces the owner with the new owner and reports whether it
+// was listed. When the line already lists the new owner, the old one is
+// removed instead of listing the new one twice.
+func (l *Line) ReplaceOwner(owner string, newOwner string) bool {
+	i := l.ownerIndex(owner)
+	if i < 0 {
+		return false
+	}
+	if j := l.ownerIndex(newOwner); j >= 0 && j != i {
+		return l.RemoveOwner(owner)
+	}
+	l.Owners[i].Value = newOwner
+	return true
+}
+
+// MapOwners replaces every owner with the result of the mapping and reports
+// whether the line changed. Each owner is mapped once from its original value,
+// so chained mappings do not apply twice. An owner mapped to an empty string
+// is removed, and an owner mapped to one the line already lists is dropped.
+// Removing every owner comments the rule out, see CommentOut.
+func (l *Line) MapOwners(mapping func(owner string) string) bool {
+	changed := false
+	owners := make([]*Owner, 0, len(l.Owners))
+	for i, owner := range l.Owners {
+		value := mapping(owner.Value)
+		if value != owner.Value {
+			changed = true
+		}
+		if value == "" || (&Line{Owners: owners}).HasOwner(value) {
+			changed = true
+			continue
+		}
+		space := owner.Space
+		if len(owners) == 0 && i > 0 {
+			space = l.Owners[0].Space
+		}
+		owners = append(owners, &Owner{Space: space, Value: value})
+	}
+	if len(owners) == 0 && len(l.Owners) > 0 {
+		l.CommentOut()
+		return true
+	}
+	l.Owners = owners
+	return changed
+}
+
+// SortOwners sorts the owners case-insensitively. The whitespace between them
+// stays where it is.
+func (l *Line) SortOwners() {
+	values := make([]string, len(l.Owners))
+	for i, owner := range l.Owners {
+		values[i] = owner.Value
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return strings.ToLower(values[i]) < strings.ToLower(values[j])
+	})
+	for i, value := range values {
+		l.Owners[i].Value = value
+	}
+}
+
+// SetComment sets the comment at the end of the line, which must start with #.
+// An empty comment removes it.
+func (l *Line) SetComment(comment string) {
+	if comment == "" {
+		l.Trailing = ""
+	} else if l.Comment == "" && l.Trailing == "" {
+		l.Trailing = " "
+	}
+	l.Comment = comment
+}
+
+// Rules returns the rule lines.
+func (f *File) Rules() []*Line {
+	var rules []*Line
+	for _, line := range f.Lines {
+		if line.IsRule() {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+// RemoveOwner removes the owner from every rule and returns the number of
+// rules it was removed from.
+func (f *File) RemoveOwner(owner string) int {
+	n := 0
+	for _, line := range f.Rules() {
+		if line.RemoveOwner(owner) {
+			n++
+		}
+	}
+	return n
+}
+
+// ReplaceOwner replaces the owner with the new owner in every rule and
+// returns the number of rules it was replaced in.
+func (f *File) ReplaceOwner(owner string, newOwner string) int {
+	n := 0
+	for _, line := range f.Rules() {
+		if line.ReplaceOwner(owner, newOwner) {
+			n++
+		}
+	}
+	return n
+}
+
+// MapOwners maps the owners of every rule and returns the number of rules that
+// changed.
+func (f *File) MapOwners(mapping func(owner string) string) int {
+	n := 0
+	for _, line := range f.Rules() {
+		if line.MapOwners(mapping) {
+			n++
+		}
+	}
+	return n
+}
+
+// SortOwners sorts the owners of every rule.
+func (f *File) SortOwners() {
+	for _, line := range f.Rules() {
+		line.SortOwners()
+	}
+}
+
+// Bytes serializes the file.
+func (f *File) Bytes() []byte {
+	var b bytes.Buffer
+	for _, line := range f.Lines {
+		b.WriteString(line.String())
+	}
+	return b.Bytes()
+}
+
+// Ruleset parses the serialized file with github.com/hmarr/codeowners to match
+// paths against it.
+func (f *File) Ruleset() (codeowners.Ruleset, error) {
+	return codeowners.ParseFile(bytes.NewReader(f.Bytes()))
+}
@@ -0,0 +1,121 @@
+package codeownersfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hmarr/codeowners"
+)
+
+const content = "# Owners of the repository\n" +
+	"\n" +
+	"*                @org/Team1   @user1 # default\n" +
+	"/my\\ docs/\t@user2\t@org/team1\r\n" +
+	"\\#hash @user1\n" +
+	"/unowned/   \n" +
+	"   # indented comment\n" +
+	"/bin/ @user3 @org/team2 docs@example.com"
+
+func TestParse(t *testing.T) {
+	file, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(content, string(file.Bytes())); diff != "" {
+		t.Errorf("unexpected serialization\n%s", diff)
+	}
+
+	want := []*Line{
+		{Number: 3, Pattern: "*", Owners: []*Owner{{Space: "                ", Value: "@org/Team1"}, {Space: "   ", Value: "@user1"}}, Trailing: " ", Comment: "# default", EOL: "\n"},
+		{Number: 4, Pattern: "/my\\ docs/", Owners: []*Owner{{Space: "\t", Value: "@user2"}, {Space: "\t", Value: "@org/team1"}}, EOL: "\r\n"},
+		{Number: 5, Pattern: "\\#hash", Owners: []*Owner{{Space: " ", Value: "@user1"}}, EOL: "\n"},
+		{Number: 6, Pattern: "/unowned/", Trailing: "   ", EOL: "\n"},
+		{Number: 8, Pattern: "/bin/", Owners: []*Owner{{Space: " ", Value: "@user3"}, {Space: " ", Value: "@org/team2"}, {Space: " ", Value: "docs@example.com"}}},
+	}
+	if diff := cmp.Diff(want, file.Rules()); diff != "" {
+		t.Errorf("unexpected rules\n%s", diff)
+	}
+	if diff := cmp.Diff("/my docs/", file.Rules()[1].UnescapedPattern()); diff != "" {
+		t.Errorf("unexpected unescaped pattern\n%s", diff)
+	}
+}
+
+func TestParseTestdata(t *testing.T) {
+	paths, err := filepath.Glob("../testdata/CODEOWNERS*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := ParseBytes(b)
+		if diff := cmp.Diff(string(b), string(file.Bytes())); diff != "" {
+			t.Errorf("%s does not round-trip\n%s", path, diff)
+		}
+
+		want, err := codeowners.LoadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var patterns, wantPatterns []string
+		for _, rule := range file.Rules() {
+			patterns = append(patterns, rule.UnescapedPattern())
+		}
+		for _, rule := range want {
+			wantPatterns = append(wantPatterns, rule.RawPattern())
+		}
+		if diff := cmp.Diff(wantPatterns, patterns); diff != "" {
+			t.Errorf("%s: unexpected patterns\n%s", path, diff)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	file := ParseBytes([]byte(content))
+
+	if diff := cmp.Diff(2, file.ReplaceOwner("@org/team1", "@org/new-team")); diff != "" {
+		t.Errorf("unexpected number of replacements\n%s", diff)
+	}
+	// @user1 is removed from the rule already listing @org/new-team.
+	file.Rules()[0].ReplaceOwner("@user1", "@org/new-team")
+	if diff := cmp.Diff(2, file.RemoveOwner("@user1")+file.RemoveOwner("@user3")); diff != "" {
+		t.Errorf("unexpected number of removals\n%s", diff)
+	}
+	file.SortOwners()
+
+	want := "# Owners of the repository\n" +
+		"\n" +
+		"*                @org/new-team # default\n" +
+		"/my\\ docs/\t@org/new-team\t@user2\r\n" +
+		"# No owner left: \\#hash @user1\n" +
+		"/unowned/   \n" +
+		"   # indented comment\n" +
+		"/bin/ @org/team2 docs@example.com"
+	if diff := cmp.Diff(want, string(file.Bytes())); diff != "" {
+		t.Errorf("unexpected content\n%s", diff)
+	}
+}
+
+func TestRemoveLastOwner(t *testing.T) {
+	file := ParseBytes([]byte("* @org/team1\n  /docs/   @gone # docs\n/src/ @gone @also-gone\n"))
+	if diff := cmp.Diff(2, file.RemoveOwner("@gone")); diff != "" {
+		t.Errorf("unexpected number of removals\n%s", diff)
+	}
+	file.Rules()[1].MapOwners(func(owner string) string { return "" })
+
+	want := "* @org/team1\n" +
+		"  # No owner left: /docs/   @gone # docs\n" +
+		"# No owner left: /src/ @also-gone\n"
+	if diff := cmp.Diff(want, string(file.Bytes())); diff != "" {
+		t.Errorf("unexpected content\n%s", diff)
+	}
+	if diff := cmp.Diff(1, len(file.Rules())); diff != "" {
+		t.Errorf("unexpected number of rules\n%s", diff)
+	}
+}
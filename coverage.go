@@ -0,0 +1,174 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+const (
+	// CoverageOwned means at least one owner of the file can approve.
+	CoverageOwned = "owned"
+	// CoverageUnowned means no rule assigns owners to the file.
+	CoverageUnowned = "unowned"
+	// CoverageUnapprovable means the file has owners but none of them can approve.
+	CoverageUnapprovable = "unapprovable"
+)
+
+// CoverageReport maps the files of a repository to their effective owners.
+type CoverageReport struct {
+	Files       []FileCoverage      `json:"files"`
+	Directories []DirectoryCoverage `json:"directories"`
+	// UnownedDirectories are the topmost directories in which no file has an owner.
+	UnownedDirectories []string `json:"unowned_directories"`
+	// UnapprovableDirectories are the topmost directories in which every file
+	// has owners but none of them can approve.
+	UnapprovableDirectories []string `json:"unapprovable_directories"`
+}
+
+// FileCoverage is the owning rule of a file.
+type FileCoverage struct {
+	Path       string   `json:"path"`
+	Pattern    string   `json:"pattern,omitempty"`
+	LineNumber int      `json:"line_number,omitempty"`
+	Owners     []string `json:"owners"`
+	Status     string   `json:"status"`
+}
+
+// DirectoryCoverage counts the files under a directory, recursively, by status.
+type DirectoryCoverage struct {
+	Path         string `json:"path"`
+	Owned        int    `json:"owned"`
+	Unowned      int    `json:"unowned"`
+	Unapprovable int    `json:"unapprovable"`
+}
+
+// ListLocalFiles lists the files tracked by Git in the repository at root as
+// slash separated paths relative to root. Untracked and ignored files, such
+// as dependencies and build output, are left out, like they are from the tree
+// of the repository on GitHub.
+func ListLocalFiles(root string) ([]string, error) {
+	out, err := exec.Command("git", "-C", root, "ls-files", "-z").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git ls-files: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// ListTreeFiles lists the files of the repository at the ref using the Git
+// Trees API.
+func ListTreeFiles(ctx context.Context, api *github.Client, org string, repo string, ref string) ([]string, error) {
+	tree, _, err := api.Git.GetTree(ctx, org, repo, ref, true)
+	if err != nil {
+		return nil, err
+	}
+	if tree.GetTruncated() {
+		log.Printf("the tree of %s/%s is truncated, so some files are missing from the coverage\n", org, repo)
+	}
+	var files []string
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			files = append(files, entry.GetPath())
+		}
+	}
+	return files, nil
+}
+
+// AnalyzeCoverage finds the owning rule of every file and summarizes which
+// directories are unowned or owned only by owners who cannot approve. The rule
+// reports must be the result of AnalyzeRules for the same ruleset.
+func AnalyzeCoverage(ruleset codeowners.Ruleset, reports []RuleReport, files []string) (*CoverageReport, error) {
+	approvable := make(map[int]bool)
+	for _, report := range reports {
+		approvable[report.LineNumber] = report.Approvable
+	}
+
+	report := &CoverageReport{
+		Files:                   []FileCoverage{},
+		Directories:             []DirectoryCoverage{},
+		UnownedDirectories:      []string{},
+		UnapprovableDirectories: []string{},
+	}
+	directories := make(map[string]*DirectoryCoverage)
+
+	sort.Strings(files)
+	for _, file := range files {
+		coverage := FileCoverage{Path: file, Owners: []string{}, Status: CoverageUnowned}
+		rule, err := ruleset.Match(file)
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil {
+			coverage.Pattern = rule.RawPattern()
+			coverage.LineNumber = rule.LineNumber
+			for _, owner := range rule.Owners {
+				coverage.Owners = append(coverage.Owners, owner.String())
+			}
+			if len(rule.Owners) > 0 {
+				coverage.Status = CoverageUnapprovable
+				if approvable[rule.LineNumber] {
+					coverage.Status = CoverageOwned
+				}
+			}
+		}
+		report.Files = append(report.Files, coverage)
+
+		for dir := path.Dir(file); ; dir = path.Dir(dir) {
+			d, ok := directories[dir]
+			if !ok {
+				d = &DirectoryCoverage{Path: dir}
+				directories[dir] = d
+			}
+			switch coverage.Status {
+			case CoverageOwned:
+				d.Owned++
+			case CoverageUnowned:
+				d.Unowned++
+			case CoverageUnapprovable:
+				d.Unapprovable++
+			}
+			if dir == "." {
+				break
+			}
+		}
+	}
+
+	var paths []string
+	for p := range directories {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		d := directories[p]
+		report.Directories = append(report.Directories, *d)
+
+		// Only the topmost directory is reported when a whole subtree is affected.
+		parent := directories[path.Dir(p)]
+		if d.Owned == 0 && d.Unapprovable == 0 && (p == "." || parent.Owned > 0 || parent.Unapprovable > 0) {
+			report.UnownedDirectories = append(report.UnownedDirectories, p)
+		}
+		if d.Owned == 0 && d.Unowned == 0 && (p == "." || parent.Owned > 0 || parent.Unowned > 0) {
+			report.UnapprovableDirectories = append(report.UnapprovableDirectories, p)
+		}
+	}
+
+	return report, nil
+}
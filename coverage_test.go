@@ -0,0 +1,116 @@
+package codeownerizer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hmarr/codeowners"
+)
+
+func TestAnalyzeCoverage(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader(`/docs/ @octocat
+/scripts/ @doctocat
+/apps/web/ @octocat
+`))
+	if err != nil {
+		t.Error(err)
+	}
+	reports := []RuleReport{
+		{Pattern: "/docs/", LineNumber: 1, Approvable: true},
+		{Pattern: "/scripts/", LineNumber: 2, Blocked: true},
+		{Pattern: "/apps/web/", LineNumber: 3, Approvable: true},
+	}
+	files := []string{
+		"docs/index.md",
+		"scripts/build.sh",
+		"scripts/ci/test.sh",
+		"apps/web/main.go",
+		"apps/api/main.go",
+		"apps/api/handler/handler.go",
+		"README.md",
+	}
+
+	report, err := AnalyzeCoverage(ruleset, reports, files)
+	if err != nil {
+		t.Error(err)
+	}
+
+	wantFiles := []FileCoverage{
+		{Path: "README.md", Owners: []string{}, Status: CoverageUnowned},
+		{Path: "apps/api/handler/handler.go", Owners: []string{}, Status: CoverageUnowned},
+		{Path: "apps/api/main.go", Owners: []string{}, Status: CoverageUnowned},
+		{Path: "apps/web/main.go", Pattern: "/apps/web/", LineNumber: 3, Owners: []string{"@octocat"}, Status: CoverageOwned},
+		{Path: "docs/index.md", Pattern: "/docs/", LineNumber: 1, Owners: []string{"@octocat"}, Status: CoverageOwned},
+		{Path: "scripts/build.sh", Pattern: "/scripts/", LineNumber: 2, Owners: []string{"@doctocat"}, Status: CoverageUnapprovable},
+		{Path: "scripts/ci/test.sh", Pattern: "/scripts/", LineNumber: 2, Owners: []string{"@doctocat"}, Status: CoverageUnapprovable},
+	}
+	if diff := cmp.Diff(wantFiles, report.Files); diff != "" {
+		t.Errorf("unexpected files\n%s", diff)
+	}
+
+	wantDirectories := []DirectoryCoverage{
+		{Path: ".", Owned: 2, Unowned: 3, Unapprovable: 2},
+		{Path: "apps", Owned: 1, Unowned: 2},
+		{Path: "apps/api", Unowned: 2},
+		{Path: "apps/api/handler", Unowned: 1},
+		{Path: "apps/web", Owned: 1},
+		{Path: "docs", Owned: 1},
+		{Path: "scripts", Unapprovable: 2},
+		{Path: "scripts/ci", Unapprovable: 1},
+	}
+	if diff := cmp.Diff(wantDirectories, report.Directories); diff != "" {
+		t.Errorf("unexpected directories\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"apps/api"}, report.UnownedDirectories); diff != "" {
+		t.Errorf("unexpected unowned directories\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"scripts"}, report.UnapprovableDirectories); diff != "" {
+		t.Errorf("unexpected unapprovable directories\n%s", diff)
+	}
+}
+
+func TestListLocalFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	write := func(name string, content string) {
+		t.Helper()
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write(".gitignore", "build/\n")
+	write("docs/README.md", "")
+	write("docs/a file.md", "")
+	write("build/out.bin", "")
+	write("untracked.txt", "")
+	git("add", ".gitignore", "docs")
+
+	// Untracked and ignored files are left out.
+	files, err := ListLocalFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{".gitignore", "docs/README.md", "docs/a file.md"}, files); diff != "" {
+		t.Errorf("unexpected files\n%s", diff)
+	}
+}
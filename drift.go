@@ -0,0 +1,108 @@
+package codeownerizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// DriftIssueTitle is the title of the issue tracking the drift of a repository.
+const DriftIssueTitle = "Code owners lost access to this repository"
+
+// DriftReport lists the code owners of a repository whose access is below the
+// permission they should have.
+type DriftReport struct {
+	Repo           string         `json:"repo"`
+	CodeownersPath string         `json:"codeowners_path"`
+	Owners         []DriftedOwner `json:"owners"`
+}
+
+// DriftedOwner is a code owner whose access is below the target permission.
+type DriftedOwner struct {
+	Owner string `json:"owner"`
+	// Principal is the slug of the team or the login of the user.
+	Principal string `json:"principal"`
+	// Permission is the direct permission of the principal on the repository,
+	// which is empty when it has no direct access.
+	Permission string `json:"permission"`
+	// Target is the permission the principal should have, or the team it
+	// should be a member of when a codeowners team is used.
+	Target string    `json:"target"`
+	Rules  []RuleRef `json:"rules"`
+}
+
+// DetectDrift checks the owners in the CODEOWNERS file on the default branch
+// of the repository without granting anything.
+func DetectDrift(ctx context.Context, api *github.Client, org string, repo string, opts *Options) (*DriftReport, error) {
+	file, err := FetchCodeownersFile(ctx, api, org, repo, "")
+	if err != nil {
+		return nil, err
+	}
+	ruleset, err := file.Ruleset()
+	if err != nil {
+		return nil, err
+	}
+	actions, err := PlanRuleset(ctx, api, org, repo, ruleset, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{
+		Repo:           org + "/" + repo,
+		CodeownersPath: file.Path,
+		Owners:         []DriftedOwner{},
+	}
+	for _, action := range actions {
+		target := action.Permission
+		if action.Type == ActionAddTeamMember {
+			target = "member of @" + org + "/" + action.Team
+		}
+		report.Owners = append(report.Owners, DriftedOwner{
+			Owner:      action.Owner,
+			Principal:  action.Principal,
+			Permission: action.OldPermission,
+			Target:     target,
+			Rules:      action.Rules,
+		})
+	}
+	return report, nil
+}
+
+// Markdown renders the report as a Markdown section.
+func (r *DriftReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", r.Repo)
+	if len(r.Owners) == 0 {
+		b.WriteString("All code owners have access.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d code owner(s) in `%s` lack access.\n\n", len(r.Owners), r.CodeownersPath)
+	b.WriteString("| Owner | Permission | Target | Rules |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, owner := range r.Owners {
+		permission := owner.Permission
+		if permission == "" {
+			permission = "none"
+		}
+		var rules []string
+		for _, rule := range owner.Rules {
+			rules = append(rules, fmt.Sprintf("`%s` (line %d)", rule.Pattern, rule.LineNumber))
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", owner.Owner, permission, owner.Target, strings.Join(rules, ", "))
+	}
+	return b.String()
+}
+
+// SyncDriftIssue opens or updates the tracking issue of the repository while
+// owners lack access, and closes it once they all have access again.
+func SyncDriftIssue(ctx context.Context, api *github.Client, org string, repo string, report *DriftReport) error {
+	if len(report.Owners) == 0 {
+		return CloseIssue(ctx, api, org, repo, DriftIssueTitle, "All code owners have access again.")
+	}
+	body := "The following code owners lack the access to approve pull requests. " +
+		"Run `codeownerizer apply` to grant them, or update CODEOWNERS.\n\n" + report.Markdown()
+	_, err := UpsertIssue(ctx, api, org, repo, DriftIssueTitle, body)
+	return err
+}
@@ -0,0 +1,106 @@
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestDetectDrift(t *testing.T) {
+	var edited github.IssueRequest
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			github.RepositoryContent{
+				Type:     github.Ptr("file"),
+				Encoding: github.Ptr(""),
+				Content:  github.Ptr("* @org/team1 @user1\n/docs/ @user2\n"),
+				SHA:      github.Ptr("sha"),
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				{
+					Login:       github.Ptr("user1"),
+					Permissions: map[string]bool{"pull": true},
+				},
+				{
+					Login:       github.Ptr("user2"),
+					Permissions: map[string]bool{"push": true, "pull": true},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposIssuesByOwnerByRepo,
+			[]github.Issue{
+				{Number: github.Ptr(1), Title: github.Ptr("Something else")},
+				{Number: github.Ptr(2), Title: github.Ptr(DriftIssueTitle), Body: github.Ptr("outdated")},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PatchReposIssuesByOwnerByRepoByIssueNumber,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/org/repo/issues/2" {
+					t.Errorf("unexpected issue edited: %s", r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&edited); err != nil {
+					t.Error(err)
+				}
+				_, _ = w.Write(mock.MustMarshal(github.Issue{Number: github.Ptr(2)}))
+			}),
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+
+	report, err := DetectDrift(context.Background(), c, "org", "repo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DriftReport{
+		Repo:           "org/repo",
+		CodeownersPath: ".github/CODEOWNERS",
+		Owners: []DriftedOwner{
+			{
+				Owner:     "@org/team1",
+				Principal: "team1",
+				Target:    "push",
+				Rules:     []RuleRef{{Pattern: "*", LineNumber: 1}},
+			},
+			{
+				Owner:      "@user1",
+				Principal:  "user1",
+				Permission: "pull",
+				Target:     "push",
+				Rules:      []RuleRef{{Pattern: "*", LineNumber: 1}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("unexpected report\n%s", diff)
+	}
+
+	if err := SyncDriftIssue(context.Background(), c, "org", "repo", report); err != nil {
+		t.Fatal(err)
+	}
+	wantBody := "The following code owners lack the access to approve pull requests. " +
+		"Run `codeownerizer apply` to grant them, or update CODEOWNERS.\n\n" +
+		"## org/repo\n\n" +
+		"2 code owner(s) in `.github/CODEOWNERS` lack access.\n\n" +
+		"| Owner | Permission | Target | Rules |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `@org/team1` | none | push | `*` (line 1) |\n" +
+		"| `@user1` | pull | push | `*` (line 1) |\n"
+	if diff := cmp.Diff(wantBody, edited.GetBody()); diff != "" {
+		t.Errorf("unexpected issue body\n%s", diff)
+	}
+}
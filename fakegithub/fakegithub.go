@@ -0,0 +1,831 @@
+// Package fakegithub is a stateful fake of the parts of the GitHub REST API
+// that codeownerizer uses. It models organizations, teams and their hierarchy,
+// repositories, collaborators, invitations and permissions, so that a grant
+// changes what the next request returns. It is meant for end-to-end tests of
+// workflows such as idempotent reconciling or runs over many repositories.
+//
+// Lists are returned in a single page.
+package fakegithub
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// permissionLevels lists the base permissions from the lowest.
+var permissionLevels = []string{"pull", "triage", "push", "maintain", "admin"}
+
+// baseRoles maps the base roles of custom repository roles and the role names
+// of the API to base permissions.
+var baseRoles = map[string]string{
+	"read":     "pull",
+	"triage":   "triage",
+	"write":    "push",
+	"maintain": "maintain",
+	"admin":    "admin",
+}
+
+// roleNames maps base permissions to their role names.
+var roleNames = map[string]string{
+	"pull":     "read",
+	"triage":   "triage",
+	"push":     "write",
+	"maintain": "maintain",
+	"admin":    "admin",
+}
+
+// User is a GitHub user.
+type User struct {
+	Login     string
+	Email     string
+	Bot       bool
+	Suspended bool
+}
+
+type org struct {
+	members     map[string]bool
+	teams       map[string]*team
+	customRoles map[string]string
+}
+
+type team struct {
+	id      int64
+	slug    string
+	parent  string
+	members map[string]bool
+	pending map[string]bool
+}
+
+type repo struct {
+	org           string
+	name          string
+	defaultBranch string
+	files         map[string]string
+	teams         map[string]string
+	collaborators map[string]string
+	invitations   map[string]string
+}
+
+// Server is a fake GitHub API server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	users     map[string]*User
+	orgs      map[string]*org
+	repos     map[string]*repo
+	nextID    int64
+	mutations int
+}
+
+// NewServer starts a fake GitHub API server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		users: make(map[string]*User),
+		orgs:  make(map[string]*org),
+		repos: make(map[string]*repo),
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// Client returns a go-github client sending requests to the server.
+func (s *Server) Client() *github.Client {
+	client := github.NewClient(s.Server.Client())
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	return client
+}
+
+// Mutations returns the number of requests that changed the state, which
+// tells whether running a workflow again changes anything.
+func (s *Server) Mutations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mutations
+}
+
+// AddUser adds a user.
+func (s *Server) AddUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[strings.ToLower(user.Login)] = &user
+}
+
+// AddOrg adds an organization with the members, adding the users that do not
+// exist yet.
+func (s *Server) AddOrg(name string, members ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.orgs[name]
+	if o == nil {
+		o = &org{members: make(map[string]bool), teams: make(map[string]*team), customRoles: make(map[string]string)}
+		s.orgs[name] = o
+	}
+	for _, member := range members {
+		s.ensureUser(member)
+		o.members[strings.ToLower(member)] = true
+	}
+}
+
+// AddTeam adds a team to the organization, as a child of the parent team
+// unless the parent is empty. Members are added to the organization as well.
+func (s *Server) AddTeam(orgName string, slug string, parent string, members ...string) {
+	s.AddOrg(orgName, members...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	t := &team{id: s.nextID, slug: slug, parent: parent, members: make(map[string]bool), pending: make(map[string]bool)}
+	for _, member := range members {
+		t.members[strings.ToLower(member)] = true
+	}
+	s.orgs[orgName].teams[slug] = t
+}
+
+// AddCustomRole adds a custom repository role based on the base role, which is
+// read, triage, write or maintain.
+func (s *Server) AddCustomRole(orgName string, name string, baseRole string) {
+	s.AddOrg(orgName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orgs[orgName].customRoles[name] = baseRole
+}
+
+// AddRepo adds a repository to the organization with main as its default
+// branch.
+func (s *Server) AddRepo(orgName string, name string) {
+	s.AddOrg(orgName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[orgName+"/"+name] = &repo{
+		org:           orgName,
+		name:          name,
+		defaultBranch: "main",
+		files:         make(map[string]string),
+		teams:         make(map[string]string),
+		collaborators: make(map[string]string),
+		invitations:   make(map[string]string),
+	}
+}
+
+// SetFile sets the content of a file on the default branch.
+func (s *Server) SetFile(orgName string, repoName string, path string, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(orgName, repoName).files[path] = content
+}
+
+// SetTeamPermission gives the team the permission on the repository. An empty
+// permission removes its access.
+func (s *Server) SetTeamPermission(orgName string, repoName string, slug string, permission string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	setOrDelete(s.repo(orgName, repoName).teams, slug, permission)
+}
+
+// SetCollaborator makes the user a direct collaborator with the permission. An
+// empty permission removes the collaborator.
+func (s *Server) SetCollaborator(orgName string, repoName string, login string, permission string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureUser(login)
+	setOrDelete(s.repo(orgName, repoName).collaborators, strings.ToLower(login), permission)
+}
+
+// TeamPermission returns the direct permission of the team on the repository.
+func (s *Server) TeamPermission(orgName string, repoName string, slug string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(orgName, repoName).teams[slug]
+}
+
+// CollaboratorPermission returns the direct permission of the user on the
+// repository.
+func (s *Server) CollaboratorPermission(orgName string, repoName string, login string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(orgName, repoName).collaborators[strings.ToLower(login)]
+}
+
+// TeamMembers returns the active members of the team.
+func (s *Server) TeamMembers(orgName string, slug string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.orgs[orgName].teams[slug].members)
+}
+
+// Invitations returns the users invited to the repository who have not
+// accepted yet.
+func (s *Server) Invitations(orgName string, repoName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.repo(orgName, repoName).invitations)
+}
+
+// AcceptInvitation makes the invited user a collaborator.
+func (s *Server) AcceptInvitation(orgName string, repoName string, login string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(orgName, repoName)
+	login = strings.ToLower(login)
+	permission, ok := r.invitations[login]
+	if !ok {
+		return fmt.Errorf("%s is not invited to %s/%s", login, orgName, repoName)
+	}
+	delete(r.invitations, login)
+	r.collaborators[login] = permission
+	return nil
+}
+
+func (s *Server) ensureUser(login string) {
+	if _, ok := s.users[strings.ToLower(login)]; !ok {
+		s.users[strings.ToLower(login)] = &User{Login: login}
+	}
+}
+
+func (s *Server) repo(orgName string, repoName string) *repo {
+	r, ok := s.repos[orgName+"/"+repoName]
+	if !ok {
+		panic(fmt.Sprintf("fakegithub: no repository %s/%s", orgName, repoName))
+	}
+	return r
+}
+
+func setOrDelete(m map[string]string, key string, value string) {
+	if value == "" {
+		delete(m, key)
+	} else {
+		m[key] = value
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// basePermission resolves a permission or a custom role to a base permission.
+func (o *org) basePermission(permission string) string {
+	if base, ok := o.customRoles[permission]; ok {
+		return baseRoles[base]
+	}
+	return permission
+}
+
+// roleName returns the role name of a permission or a custom role.
+func roleName(permission string) string {
+	if name, ok := roleNames[permission]; ok {
+		return name
+	}
+	return permission
+}
+
+func level(permission string) int {
+	for i, p := range permissionLevels {
+		if p == permission {
+			return i
+		}
+	}
+	return -1
+}
+
+func permissionsMap(permission string) map[string]bool {
+	permissions := make(map[string]bool)
+	for i, p := range permissionLevels {
+		permissions[p] = i <= level(permission)
+	}
+	return permissions
+}
+
+// higher returns the higher of two permissions or custom roles.
+func (o *org) higher(a string, b string) string {
+	if level(o.basePermission(b)) > level(o.basePermission(a)) {
+		return b
+	}
+	return a
+}
+
+// teamPermission returns the permission of the team on the repository,
+// including the one inherited from its ancestors.
+func (s *Server) teamPermission(r *repo, slug string) string {
+	o := s.orgs[r.org]
+	permission := ""
+	for t := o.teams[slug]; t != nil; t = o.teams[t.parent] {
+		if p, ok := r.teams[t.slug]; ok {
+			permission = o.higher(permission, p)
+		}
+		if t.parent == "" {
+			break
+		}
+	}
+	return permission
+}
+
+// userPermission returns the permission of the user on the repository, either
+// direct or through the teams the user is an active member of.
+func (s *Server) userPermission(r *repo, login string) string {
+	o := s.orgs[r.org]
+	permission := r.collaborators[login]
+	for slug, t := range o.teams {
+		if t.members[login] {
+			permission = o.higher(permission, s.teamPermission(r, slug))
+		}
+	}
+	return permission
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler func(w http.ResponseWriter, r *http.Request) (any, int)) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			body, status := handler(w, r)
+			if r.Method != http.MethodGet && status < 300 {
+				s.mutations++
+			}
+			s.mu.Unlock()
+			writeJSON(w, status, body)
+		})
+	}
+
+	handle("GET /repos/{owner}/{repo}", s.getRepo)
+	handle("GET /repos/{owner}/{repo}/contents/{path...}", s.getContents)
+	handle("GET /repos/{owner}/{repo}/teams", s.listRepoTeams)
+	handle("GET /repos/{owner}/{repo}/collaborators", s.listCollaborators)
+	handle("PUT /repos/{owner}/{repo}/collaborators/{user}", s.addCollaborator)
+	handle("DELETE /repos/{owner}/{repo}/collaborators/{user}", s.removeCollaborator)
+	handle("GET /repos/{owner}/{repo}/collaborators/{user}/permission", s.getPermissionLevel)
+	handle("GET /repos/{owner}/{repo}/invitations", s.listInvitations)
+	handle("GET /orgs/{org}/teams", s.listTeams)
+	handle("GET /orgs/{org}/teams/{slug}", s.getTeam)
+	handle("GET /orgs/{org}/teams/{slug}/teams", s.listChildTeams)
+	handle("GET /orgs/{org}/teams/{slug}/members", s.listTeamMembers)
+	handle("GET /orgs/{org}/teams/{slug}/memberships/{user}", s.getTeamMembership)
+	handle("PUT /orgs/{org}/teams/{slug}/memberships/{user}", s.addTeamMembership)
+	handle("GET /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", s.isTeamRepo)
+	handle("PUT /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", s.addTeamRepo)
+	handle("DELETE /orgs/{org}/teams/{slug}/repos/{owner}/{repo}", s.removeTeamRepo)
+	handle("GET /orgs/{org}/teams/{slug}/repos", s.listTeamRepos)
+	handle("GET /orgs/{org}/custom-repository-roles", s.listCustomRoles)
+	handle("GET /users/{user}", s.getUser)
+	handle("GET /search/users", s.searchUsers)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func notFound() (any, int) {
+	return map[string]string{"message": "Not Found"}, http.StatusNotFound
+}
+
+func unprocessable(message string) (any, int) {
+	return map[string]string{"message": message}, http.StatusUnprocessableEntity
+}
+
+func (s *Server) lookupRepo(r *http.Request) (*repo, bool) {
+	repo, ok := s.repos[r.PathValue("owner")+"/"+r.PathValue("repo")]
+	return repo, ok
+}
+
+func (s *Server) lookupTeam(r *http.Request) (*org, *team, bool) {
+	o, ok := s.orgs[r.PathValue("org")]
+	if !ok {
+		return nil, nil, false
+	}
+	t, ok := o.teams[r.PathValue("slug")]
+	return o, t, ok
+}
+
+func (s *Server) getRepo(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	return s.repository(repo), http.StatusOK
+}
+
+func (s *Server) repository(r *repo) *github.Repository {
+	return &github.Repository{
+		Name:          github.Ptr(r.name),
+		FullName:      github.Ptr(r.org + "/" + r.name),
+		Owner:         &github.User{Login: github.Ptr(r.org)},
+		DefaultBranch: github.Ptr(r.defaultBranch),
+	}
+}
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	if ref := r.URL.Query().Get("ref"); ref != "" && ref != repo.defaultBranch {
+		return notFound()
+	}
+	path := r.PathValue("path")
+	content, ok := repo.files[path]
+	if !ok {
+		return notFound()
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write([]byte(content))
+	return &github.RepositoryContent{
+		Type:     github.Ptr("file"),
+		Path:     github.Ptr(path),
+		Encoding: github.Ptr("base64"),
+		Content:  github.Ptr(base64.StdEncoding.EncodeToString([]byte(content))),
+		SHA:      github.Ptr(hex.EncodeToString(h.Sum(nil))),
+	}, http.StatusOK
+}
+
+func (s *Server) team(o *org, t *team) *github.Team {
+	return &github.Team{ID: github.Ptr(t.id), Slug: github.Ptr(t.slug), Name: github.Ptr(t.slug)}
+}
+
+func (s *Server) listRepoTeams(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	o := s.orgs[repo.org]
+	teams := []*github.Team{}
+	for _, slug := range sortedKeys(repo.teams) {
+		permission := repo.teams[slug]
+		team := s.team(o, o.teams[slug])
+		team.Permission = github.Ptr(permission)
+		team.Permissions = permissionsMap(o.basePermission(permission))
+		teams = append(teams, team)
+	}
+	return teams, http.StatusOK
+}
+
+func (s *Server) listCollaborators(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	o := s.orgs[repo.org]
+	logins := make(map[string]bool)
+	for login := range repo.collaborators {
+		logins[login] = true
+	}
+	for _, t := range o.teams {
+		for login := range t.members {
+			logins[login] = true
+		}
+	}
+	collaborators := []*github.User{}
+	for _, login := range sortedKeys(logins) {
+		permission := s.userPermission(repo, login)
+		if permission == "" {
+			continue
+		}
+		collaborators = append(collaborators, &github.User{
+			Login:       github.Ptr(s.users[login].Login),
+			RoleName:    github.Ptr(roleName(permission)),
+			Permissions: permissionsMap(o.basePermission(permission)),
+		})
+	}
+	return collaborators, http.StatusOK
+}
+
+func (s *Server) addCollaborator(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	login := strings.ToLower(r.PathValue("user"))
+	user, ok := s.users[login]
+	if !ok {
+		return notFound()
+	}
+	var opts github.RepositoryAddCollaboratorOptions
+	_ = json.NewDecoder(r.Body).Decode(&opts)
+	permission := opts.Permission
+	if permission == "" {
+		permission = "push"
+	}
+	o := s.orgs[repo.org]
+	if level(o.basePermission(permission)) < 0 {
+		return unprocessable("invalid permission: " + permission)
+	}
+
+	// Organization members and existing collaborators get the permission at
+	// once, while anyone else is invited.
+	if _, ok := repo.collaborators[login]; ok || o.members[login] {
+		repo.collaborators[login] = permission
+		return nil, http.StatusNoContent
+	}
+	repo.invitations[login] = permission
+	s.nextID++
+	return &github.CollaboratorInvitation{
+		ID:          github.Ptr(s.nextID),
+		Repo:        s.repository(repo),
+		Invitee:     &github.User{Login: github.Ptr(user.Login)},
+		Permissions: github.Ptr(roleName(permission)),
+	}, http.StatusCreated
+}
+
+func (s *Server) removeCollaborator(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	login := strings.ToLower(r.PathValue("user"))
+	delete(repo.collaborators, login)
+	delete(repo.invitations, login)
+	return nil, http.StatusNoContent
+}
+
+func (s *Server) getPermissionLevel(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	login := strings.ToLower(r.PathValue("user"))
+	user, ok := s.users[login]
+	if !ok {
+		return notFound()
+	}
+	permission := s.userPermission(repo, login)
+	// The legacy permission is one of admin, write, read and none.
+	legacy := "none"
+	switch s.orgs[repo.org].basePermission(permission) {
+	case "admin":
+		legacy = "admin"
+	case "maintain", "push":
+		legacy = "write"
+	case "triage", "pull":
+		legacy = "read"
+	}
+	return &github.RepositoryPermissionLevel{
+		Permission: github.Ptr(legacy),
+		RoleName:   github.Ptr(roleName(permission)),
+		User:       &github.User{Login: github.Ptr(user.Login)},
+	}, http.StatusOK
+}
+
+func (s *Server) listInvitations(w http.ResponseWriter, r *http.Request) (any, int) {
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	invitations := []*github.RepositoryInvitation{}
+	for i, login := range sortedKeys(repo.invitations) {
+		invitations = append(invitations, &github.RepositoryInvitation{
+			ID:          github.Ptr(int64(i + 1)),
+			Repo:        s.repository(repo),
+			Invitee:     &github.User{Login: github.Ptr(s.users[login].Login)},
+			Permissions: github.Ptr(roleName(repo.invitations[login])),
+		})
+	}
+	return invitations, http.StatusOK
+}
+
+func (s *Server) listTeams(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, ok := s.orgs[r.PathValue("org")]
+	if !ok {
+		return notFound()
+	}
+	teams := []*github.Team{}
+	for _, slug := range sortedKeys(o.teams) {
+		teams = append(teams, s.team(o, o.teams[slug]))
+	}
+	return teams, http.StatusOK
+}
+
+func (s *Server) getTeam(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	return s.team(o, t), http.StatusOK
+}
+
+func (s *Server) listChildTeams(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	teams := []*github.Team{}
+	for _, slug := range sortedKeys(o.teams) {
+		if o.teams[slug].parent == t.slug {
+			teams = append(teams, s.team(o, o.teams[slug]))
+		}
+	}
+	return teams, http.StatusOK
+}
+
+// listTeamMembers lists the members of the team and of its descendants, like
+// GitHub does by default.
+func (s *Server) listTeamMembers(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	logins := make(map[string]bool)
+	var collect func(t *team)
+	collect = func(t *team) {
+		for login := range t.members {
+			logins[login] = true
+		}
+		for _, child := range o.teams {
+			if child.parent == t.slug {
+				collect(child)
+			}
+		}
+	}
+	collect(t)
+
+	members := []*github.User{}
+	for _, login := range sortedKeys(logins) {
+		user := s.users[login]
+		userType := "User"
+		if user.Bot {
+			userType = "Bot"
+		}
+		members = append(members, &github.User{Login: github.Ptr(user.Login), Type: github.Ptr(userType)})
+	}
+	return members, http.StatusOK
+}
+
+func (s *Server) getTeamMembership(w http.ResponseWriter, r *http.Request) (any, int) {
+	_, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	login := strings.ToLower(r.PathValue("user"))
+	switch {
+	case t.members[login]:
+		return &github.Membership{State: github.Ptr("active"), Role: github.Ptr("member")}, http.StatusOK
+	case t.pending[login]:
+		return &github.Membership{State: github.Ptr("pending"), Role: github.Ptr("member")}, http.StatusOK
+	default:
+		return notFound()
+	}
+}
+
+// addTeamMembership adds an organization member to the team at once, and
+// invites anyone else to the organization with a pending membership.
+func (s *Server) addTeamMembership(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	login := strings.ToLower(r.PathValue("user"))
+	if _, ok := s.users[login]; !ok {
+		return notFound()
+	}
+	if o.members[login] {
+		t.members[login] = true
+		return &github.Membership{State: github.Ptr("active"), Role: github.Ptr("member")}, http.StatusOK
+	}
+	t.pending[login] = true
+	return &github.Membership{State: github.Ptr("pending"), Role: github.Ptr("member")}, http.StatusOK
+}
+
+func (s *Server) isTeamRepo(w http.ResponseWriter, r *http.Request) (any, int) {
+	_, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	permission := s.teamPermission(repo, t.slug)
+	if permission == "" {
+		return notFound()
+	}
+	repository := s.repository(repo)
+	repository.RoleName = github.Ptr(roleName(permission))
+	repository.Permissions = permissionsMap(s.orgs[repo.org].basePermission(permission))
+	return repository, http.StatusOK
+}
+
+func (s *Server) addTeamRepo(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	var opts github.TeamAddTeamRepoOptions
+	_ = json.NewDecoder(r.Body).Decode(&opts)
+	permission := opts.Permission
+	if permission == "" {
+		permission = "push"
+	}
+	if level(o.basePermission(permission)) < 0 {
+		return unprocessable("invalid permission: " + permission)
+	}
+	repo.teams[t.slug] = permission
+	return nil, http.StatusNoContent
+}
+
+func (s *Server) removeTeamRepo(w http.ResponseWriter, r *http.Request) (any, int) {
+	_, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	repo, ok := s.lookupRepo(r)
+	if !ok {
+		return notFound()
+	}
+	delete(repo.teams, t.slug)
+	return nil, http.StatusNoContent
+}
+
+func (s *Server) listTeamRepos(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, t, ok := s.lookupTeam(r)
+	if !ok {
+		return notFound()
+	}
+	repos := []*github.Repository{}
+	for _, fullName := range sortedKeys(s.repos) {
+		repo := s.repos[fullName]
+		if repo.org != r.PathValue("org") {
+			continue
+		}
+		permission := s.teamPermission(repo, t.slug)
+		if permission == "" {
+			continue
+		}
+		repository := s.repository(repo)
+		repository.RoleName = github.Ptr(roleName(permission))
+		repository.Permissions = permissionsMap(o.basePermission(permission))
+		repos = append(repos, repository)
+	}
+	return repos, http.StatusOK
+}
+
+func (s *Server) listCustomRoles(w http.ResponseWriter, r *http.Request) (any, int) {
+	o, ok := s.orgs[r.PathValue("org")]
+	if !ok {
+		return notFound()
+	}
+	roles := []*github.CustomRepoRoles{}
+	for _, name := range sortedKeys(o.customRoles) {
+		roles = append(roles, &github.CustomRepoRoles{Name: github.Ptr(name), BaseRole: github.Ptr(o.customRoles[name])})
+	}
+	return &github.OrganizationCustomRepoRoles{TotalCount: github.Ptr(len(roles)), CustomRepoRoles: roles}, http.StatusOK
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) (any, int) {
+	user, ok := s.users[strings.ToLower(r.PathValue("user"))]
+	if !ok {
+		return notFound()
+	}
+	result := &github.User{Login: github.Ptr(user.Login), Type: github.Ptr("User")}
+	if user.Bot {
+		result.Type = github.Ptr("Bot")
+	}
+	if user.Email != "" {
+		result.Email = github.Ptr(user.Email)
+	}
+	if user.Suspended {
+		result.SuspendedAt = &github.Timestamp{Time: time.Unix(0, 0).UTC()}
+	}
+	return result, http.StatusOK
+}
+
+// searchUsers supports the "<email> in:email" queries used to resolve email
+// owners.
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) (any, int) {
+	email, ok := strings.CutSuffix(r.URL.Query().Get("q"), " in:email")
+	if !ok {
+		return unprocessable("only email searches are supported")
+	}
+	users := []*github.User{}
+	for _, login := range sortedKeys(s.users) {
+		if user := s.users[login]; user.Email != "" && strings.EqualFold(user.Email, email) {
+			users = append(users, &github.User{Login: github.Ptr(user.Login)})
+		}
+	}
+	return &github.UsersSearchResult{Total: github.Ptr(len(users)), Users: users}, http.StatusOK
+}
@@ -0,0 +1,216 @@
+package fakegithub_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/fakegithub"
+	"github.com/hmarr/codeowners"
+)
+
+func newServer(t *testing.T) *fakegithub.Server {
+	t.Helper()
+	s := fakegithub.NewServer()
+	t.Cleanup(s.Close)
+	s.AddOrg("org", "member1")
+	s.AddUser(fakegithub.User{Login: "outsider1", Email: "outsider1@example.com"})
+	s.AddTeam("org", "parent", "", "member2")
+	s.AddTeam("org", "child", "parent")
+	s.AddTeam("org", "team1", "")
+	return s
+}
+
+func TestReconcile(t *testing.T) {
+	s := newServer(t)
+	s.AddRepo("org", "repo")
+	s.SetFile("org", "repo", ".github/CODEOWNERS", "* @org/team1 @org/child @member1 outsider1@example.com @member2\n")
+	s.SetTeamPermission("org", "repo", "parent", "push")
+	s.SetTeamPermission("org", "repo", "team1", "pull")
+
+	ctx := context.Background()
+	api := s.Client()
+	reconcile := codeownerizer.NewRepositoryReconciler(api, &codeownerizer.Options{}, nil)
+	if err := reconcile(ctx, "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// child inherits push from parent, and member2 has it through parent.
+	if diff := cmp.Diff("push", s.TeamPermission("org", "repo", "team1")); diff != "" {
+		t.Errorf("unexpected team1 permission\n%s", diff)
+	}
+	if diff := cmp.Diff("", s.TeamPermission("org", "repo", "child")); diff != "" {
+		t.Errorf("unexpected child permission\n%s", diff)
+	}
+	if diff := cmp.Diff("push", s.CollaboratorPermission("org", "repo", "member1")); diff != "" {
+		t.Errorf("unexpected member1 permission\n%s", diff)
+	}
+	if diff := cmp.Diff("", s.CollaboratorPermission("org", "repo", "member2")); diff != "" {
+		t.Errorf("unexpected member2 permission\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"outsider1"}, s.Invitations("org", "repo")); diff != "" {
+		t.Errorf("unexpected invitations\n%s", diff)
+	}
+
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @org/team1 @org/child @member1 outsider1@example.com @member2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	convergence, err := codeownerizer.VerifyConvergence(ctx, api, "org", "repo", ruleset, &codeownerizer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !convergence.Converged() {
+		t.Errorf("expected convergence, got %v", convergence.Remaining)
+	}
+	if diff := cmp.Diff(1, len(convergence.Pending)); diff != "" {
+		t.Errorf("unexpected pending invitations\n%s", diff)
+	}
+
+	// Only the pending invitation is sent again.
+	mutations := s.Mutations()
+	if err := reconcile(ctx, "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(mutations+1, s.Mutations()); diff != "" {
+		t.Errorf("unexpected mutations\n%s", diff)
+	}
+
+	// Nothing changes once the invitation is accepted.
+	if err := s.AcceptInvitation("org", "repo", "outsider1"); err != nil {
+		t.Fatal(err)
+	}
+	mutations = s.Mutations()
+	if err := reconcile(ctx, "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(mutations, s.Mutations()); diff != "" {
+		t.Errorf("unexpected mutations\n%s", diff)
+	}
+}
+
+func TestVerifyConvergence(t *testing.T) {
+	s := newServer(t)
+	s.AddRepo("org", "repo")
+	content := "* @org/team1 @org/deleted-team\n"
+	s.SetFile("org", "repo", "CODEOWNERS", content)
+
+	ctx := context.Background()
+	api := s.Client()
+	if err := codeownerizer.NewRepositoryReconciler(api, &codeownerizer.Options{}, nil)(ctx, "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Granting the deleted team fails, so it stays below the target.
+	ruleset, err := codeowners.ParseFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	convergence, err := codeownerizer.VerifyConvergence(ctx, api, "org", "repo", ruleset, &codeownerizer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, action := range convergence.Remaining {
+		remaining = append(remaining, action.String())
+	}
+	if diff := cmp.Diff([]string{"grant push to team deleted-team"}, remaining); diff != "" {
+		t.Errorf("unexpected remaining actions\n%s", diff)
+	}
+}
+
+func TestDetectDriftAcrossRepositories(t *testing.T) {
+	s := newServer(t)
+	for _, repo := range []string{"repo1", "repo2"} {
+		s.AddRepo("org", repo)
+		s.SetFile("org", repo, "CODEOWNERS", "* @org/team1\n")
+		s.SetTeamPermission("org", repo, "team1", "push")
+	}
+
+	// Someone removes team1 from repo2 by hand.
+	ctx := context.Background()
+	api := s.Client()
+	if err := codeownerizer.NewRESTBackend(api).RevokeTeam(ctx, "org", "repo2", "team1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var drifted []string
+	for _, repo := range []string{"repo1", "repo2"} {
+		report, err := codeownerizer.DetectDrift(ctx, api, "org", repo, &codeownerizer.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, owner := range report.Owners {
+			drifted = append(drifted, report.Repo+" "+owner.Owner)
+		}
+	}
+	if diff := cmp.Diff([]string{"org/repo2 @org/team1"}, drifted); diff != "" {
+		t.Errorf("unexpected drift\n%s", diff)
+	}
+}
+
+func TestReconcileManifest(t *testing.T) {
+	s := newServer(t)
+	s.AddCustomRole("org", "approver", "write")
+	s.AddRepo("org", "repo1")
+	s.SetFile("org", "repo1", ".github/CODEOWNERS", "* @org/team1\n")
+	s.AddRepo("org", "repo2")
+	s.SetFile("org", "repo2", "docs/CODEOWNERS", "* @member1\n")
+	s.AddRepo("org", "repo3")
+	// repo4 needs no grant, so its plan is reused to check it.
+	s.AddRepo("org", "repo4")
+	s.SetFile("org", "repo4", "CODEOWNERS", "* @org/team1\n")
+	s.SetTeamPermission("org", "repo4", "team1", "push")
+
+	manifest := &codeownerizer.RepoManifest{Repos: []codeownerizer.ManifestRepo{
+		{Repo: "org/repo1"},
+		{Repo: "org/repo2", Path: "docs/CODEOWNERS", Permission: "approver"},
+		{Repo: "org/repo3"},
+		{Repo: "org/repo4"},
+	}}
+	results := codeownerizer.ReconcileManifest(context.Background(), s.Client(), manifest, &codeownerizer.Options{})
+
+	var summary []string
+	for _, result := range results {
+		status := "ok"
+		if result.Err != nil {
+			status = result.Err.Error()
+		}
+		summary = append(summary, fmt.Sprintf("%s %d %t %s", result.Repo, len(result.Actions), result.Failed(), status))
+	}
+	want := []string{
+		"org/repo1 1 false ok",
+		"org/repo2 1 false ok",
+		"org/repo3 0 true no CODEOWNERS file found in org/repo3",
+		"org/repo4 0 false ok",
+	}
+	if diff := cmp.Diff(want, summary); diff != "" {
+		t.Errorf("unexpected results\n%s", diff)
+	}
+	if diff := cmp.Diff("approver", s.CollaboratorPermission("org", "repo2", "member1")); diff != "" {
+		t.Errorf("unexpected member1 permission\n%s", diff)
+	}
+}
+
+func TestCodeownersTeam(t *testing.T) {
+	s := newServer(t)
+	s.AddRepo("org", "repo")
+	s.SetFile("org", "repo", "CODEOWNERS", "* @member1 @outsider1\n")
+
+	ctx := context.Background()
+	reconcile := codeownerizer.NewRepositoryReconciler(s.Client(), &codeownerizer.Options{CodeownersTeam: "team1"}, nil)
+	if err := reconcile(ctx, "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// outsider1 is invited to the organization and not a member yet.
+	if diff := cmp.Diff([]string{"member1"}, s.TeamMembers("org", "team1")); diff != "" {
+		t.Errorf("unexpected team1 members\n%s", diff)
+	}
+	if diff := cmp.Diff("push", s.TeamPermission("org", "repo", "team1")); diff != "" {
+		t.Errorf("unexpected team1 permission\n%s", diff)
+	}
+}
@@ -0,0 +1,100 @@
+package codeownerizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// CodeownersLocations are the paths GitHub looks for a CODEOWNERS file at, in
+// order of precedence.
+var CodeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeownersFile is a CODEOWNERS file fetched from a repository.
+type CodeownersFile struct {
+	Path string
+	// SHA is the git blob SHA of the file.
+	SHA     string
+	Content []byte
+}
+
+// Ruleset parses the file into CODEOWNERS rules.
+func (f *CodeownersFile) Ruleset() (codeowners.Ruleset, error) {
+	return codeowners.ParseFile(bytes.NewReader(f.Content))
+}
+
+// FetchCodeownersFile fetches the CODEOWNERS file of the repository at the ref
+// from the first standard location it exists at. An empty ref means the
+// default branch.
+func FetchCodeownersFile(ctx context.Context, api *github.Client, org string, repo string, ref string) (*CodeownersFile, error) {
+	for _, path := range CodeownersLocations {
+		file, err := GetCodeownersFile(ctx, api, org, repo, ref, path)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			return file, nil
+		}
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file found in %s/%s", org, repo)
+}
+
+// GetCodeownersFile fetches the CODEOWNERS file at the path. It returns nil
+// without an error when the file does not exist.
+func GetCodeownersFile(ctx context.Context, api *github.Client, org string, repo string, ref string, path string) (*CodeownersFile, error) {
+	content, _, resp, err := api.Repositories.GetContents(ctx, org, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	decoded, err := content.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return &CodeownersFile{
+		Path:    path,
+		SHA:     content.GetSHA(),
+		Content: []byte(decoded),
+	}, nil
+}
+
+// ReadCodeownersFile reads the CODEOWNERS file under root from the first
+// standard location it exists at.
+func ReadCodeownersFile(root string) (*CodeownersFile, error) {
+	for _, path := range CodeownersLocations {
+		content, err := os.ReadFile(filepath.Join(root, path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &CodeownersFile{
+			Path:    path,
+			SHA:     gitBlobSHA(content),
+			Content: content,
+		}, nil
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file found in %s", root)
+}
+
+// gitBlobSHA computes the SHA git and the GitHub API identify the content by.
+func gitBlobSHA(content []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
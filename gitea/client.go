@@ -0,0 +1,175 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grezar/codeownerizer/internal/forge"
+)
+
+// pageSize is the default maximum page size of Gitea.
+const pageSize = 50
+
+// Repository is a Gitea repository.
+type Repository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         *User  `json:"owner"`
+}
+
+// User is a Gitea user.
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+// Team is a team of a Gitea organization. Its permission applies to all of its
+// repositories.
+type Team struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Permission string            `json:"permission"`
+	UnitsMap   map[string]string `json:"units_map"`
+}
+
+// codePermission returns the permission of the team on the code of its
+// repositories, which decides whether its members can approve.
+func (t *Team) codePermission() string {
+	if p := t.UnitsMap["repo.code"]; level(p) > level(t.Permission) {
+		return p
+	}
+	return t.Permission
+}
+
+// ErrorResponse is an error returned by the Gitea API.
+type ErrorResponse = forge.ErrorResponse
+
+// IsNotFound reports whether the error is a 404 response.
+func IsNotFound(err error) bool {
+	return forge.IsNotFound(err)
+}
+
+// Client is a client of the Gitea API, which Forgejo shares.
+type Client struct {
+	api *forge.Client
+}
+
+// NewClient returns a client of the Gitea instance at the URL, authenticating
+// with the access token. The HTTP client defaults to http.DefaultClient.
+func NewClient(baseURL string, token string, httpClient *http.Client) *Client {
+	api := &forge.Client{
+		API:        "Gitea",
+		BaseURL:    strings.TrimSuffix(baseURL, "/") + "/api/v1",
+		AuthHeader: "Authorization",
+		HTTPClient: httpClient,
+	}
+	if token != "" {
+		api.AuthValue = "token " + token
+	}
+	return &Client{api: api}
+}
+
+// do sends a request to the path, which must be escaped, and decodes the
+// response into out unless it is nil.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any) error {
+	_, err := c.api.Do(ctx, method, path, query, body, out)
+	return err
+}
+
+// list pages through a list endpoint until it has read as many items as the
+// X-Total-Count header announces, or, without the header, until a page is
+// empty. A page that is not full does not mean it is the last one, since
+// instances can cap the page size below the requested limit.
+func list[T any](ctx context.Context, c *Client, path string) ([]T, error) {
+	all := []T{}
+	for page := 1; ; page++ {
+		var items []T
+		query := url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(pageSize)}}
+		resp, err := c.api.Do(ctx, http.MethodGet, path, query, nil, &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) == 0 {
+			return all, nil
+		}
+		if total, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil && len(all) >= total {
+			return all, nil
+		}
+	}
+}
+
+func repoPath(owner string, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+// GetRepository gets the repository.
+func (c *Client) GetRepository(ctx context.Context, owner string, repo string) (*Repository, error) {
+	var r Repository
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo), nil, nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListRepoTeams lists the teams with access to the repository.
+func (c *Client) ListRepoTeams(ctx context.Context, owner string, repo string) ([]*Team, error) {
+	return list[*Team](ctx, c, repoPath(owner, repo)+"/teams")
+}
+
+// AddRepoTeam gives the team access to the repository with the team's
+// permission.
+func (c *Client) AddRepoTeam(ctx context.Context, owner string, repo string, team string) error {
+	return c.do(ctx, http.MethodPut, repoPath(owner, repo)+"/teams/"+url.PathEscape(team), nil, nil, nil)
+}
+
+// ListOrgTeams lists the teams of the organization.
+func (c *Client) ListOrgTeams(ctx context.Context, org string) ([]*Team, error) {
+	return list[*Team](ctx, c, "/orgs/"+url.PathEscape(org)+"/teams")
+}
+
+// GetCollaboratorPermission gets the permission of the user on the
+// repository: none, read, write, admin or owner.
+func (c *Client) GetCollaboratorPermission(ctx context.Context, owner string, repo string, login string) (string, error) {
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo)+"/collaborators/"+url.PathEscape(login)+"/permission", nil, nil, &p); err != nil {
+		return "", err
+	}
+	return p.Permission, nil
+}
+
+// AddCollaborator adds the user to the repository as a collaborator with the
+// permission, or changes the permission of a collaborator.
+func (c *Client) AddCollaborator(ctx context.Context, owner string, repo string, login string, permission string) error {
+	body := map[string]string{"permission": permission}
+	return c.do(ctx, http.MethodPut, repoPath(owner, repo)+"/collaborators/"+url.PathEscape(login), nil, body, nil)
+}
+
+// GetUser gets the user.
+func (c *Client) GetUser(ctx context.Context, login string) (*User, error) {
+	var u User
+	if err := c.do(ctx, http.MethodGet, "/users/"+url.PathEscape(login), nil, nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetRawFile gets the content of a file at the ref. An empty ref reads the
+// default branch.
+func (c *Client) GetRawFile(ctx context.Context, owner string, repo string, path string, ref string) ([]byte, error) {
+	query := url.Values{}
+	if ref != "" {
+		query.Set("ref", ref)
+	}
+	var content []byte
+	if err := c.do(ctx, http.MethodGet, repoPath(owner, repo)+"/raw/"+(&url.URL{Path: path}).EscapedPath(), query, nil, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
@@ -0,0 +1,64 @@
+package gitea
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Rule is a line of a Gitea CODEOWNERS file. Gitea patterns are regular
+// expressions, and a leading ! negates them.
+type Rule struct {
+	Pattern string
+	Negated bool
+	// Owners are the owners as written, @user or @org/team.
+	Owners     []string
+	LineNumber int
+}
+
+// File is a parsed Gitea CODEOWNERS file.
+type File struct {
+	// Path is where the file was found in the repository.
+	Path  string
+	Rules []Rule
+}
+
+// Parse parses a Gitea CODEOWNERS file.
+func Parse(r io.Reader) (*File, error) {
+	file := &File{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := Rule{Pattern: fields[0], LineNumber: lineNumber}
+		if strings.HasPrefix(rule.Pattern, "!") {
+			rule.Pattern, rule.Negated = rule.Pattern[1:], true
+		}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			if !strings.HasPrefix(owner, "@") || len(owner) == 1 || strings.Count(owner, "/") > 1 {
+				return nil, fmt.Errorf("line %d: invalid owner: %s", lineNumber, owner)
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+		file.Rules = append(file.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// ParseBytes parses the content of a Gitea CODEOWNERS file.
+func ParseBytes(content []byte) (*File, error) {
+	return Parse(bytes.NewReader(content))
+}
@@ -0,0 +1,231 @@
+// Package gitea grants the code owners of Gitea and Forgejo repositories the
+// access they need to approve pull requests, through collaborators and teams
+// with Gitea's read, write and admin permissions. It plans the same actions as
+// the codeownerizer package for GitHub.
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/internal/forge"
+)
+
+// codeownersPaths are where Gitea looks for the CODEOWNERS file, in order.
+var codeownersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitea/CODEOWNERS"}
+
+// permissionLevels lists the permissions from the lowest.
+var permissionLevels = []string{"none", "read", "write", "admin", "owner"}
+
+func level(permission string) int {
+	for i, p := range permissionLevels {
+		if p == permission {
+			return i
+		}
+	}
+	return 0
+}
+
+// grantPermissions are the permissions that can be granted to collaborators
+// and let them approve. The owner permission cannot be granted.
+var grantPermissions = map[string]bool{"write": true, "admin": true}
+
+// Options configures which grants are made on Gitea.
+type Options struct {
+	// Permission is the permission granted to user owners, write or admin.
+	// Defaults to write, the lowest permission that can approve.
+	Permission string
+
+	// IgnoredOwners are owners, as written in CODEOWNERS, that are never
+	// granted.
+	IgnoredOwners []string
+}
+
+func (o *Options) permission() string {
+	if o.Permission == "" {
+		return "write"
+	}
+	return o.Permission
+}
+
+func (o *Options) isIgnored(owner string) bool {
+	return forge.IsIgnored(o.IgnoredOwners, owner)
+}
+
+// FetchCodeownersFile fetches and parses the CODEOWNERS file of the repository
+// at the ref, looking in the same locations as Gitea.
+func FetchCodeownersFile(ctx context.Context, c *Client, owner string, repo string, ref string) (*File, error) {
+	for _, path := range codeownersPaths {
+		content, err := c.GetRawFile(ctx, owner, repo, path, ref)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		file, err := ParseBytes(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		file.Path = path
+		return file, nil
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file was found in %s/%s", owner, repo)
+}
+
+// Plan computes the actions that give the owners of the rules the permission,
+// without changing anything. Users are added as collaborators, and teams are
+// added to the repository. Owners that cannot be checked or granted are logged
+// and left out of the plan.
+func Plan(ctx context.Context, c *Client, owner string, repo string, file *File, opts *Options) ([]codeownerizer.Action, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if !grantPermissions[opts.permission()] {
+		return nil, fmt.Errorf("invalid permission: %s, use write or admin", opts.permission())
+	}
+
+	repoTeams, err := c.ListRepoTeams(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	// Only organizations have teams, so the teams of a repository owned by a
+	// user are listed on demand and fail then.
+	var orgTeams []*Team
+
+	var actions []codeownerizer.Action
+	planned := make(map[string]bool)
+	for _, rule := range file.Rules {
+		for _, o := range rule.Owners {
+			if planned[o] || opts.isIgnored(o) {
+				continue
+			}
+			planned[o] = true
+
+			var action *codeownerizer.Action
+			if org, name, ok := strings.Cut(strings.TrimPrefix(o, "@"), "/"); ok {
+				if orgTeams == nil && strings.EqualFold(org, owner) {
+					if orgTeams, err = c.ListOrgTeams(ctx, org); err != nil {
+						return nil, err
+					}
+				}
+				action, err = planTeamOwner(owner, repoTeams, orgTeams, o, org, name)
+			} else {
+				action, err = planUserOwner(ctx, c, owner, repo, o, opts)
+			}
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+			if action != nil {
+				actions = append(actions, *action)
+			}
+		}
+	}
+	return annotateRules(actions, file), nil
+}
+
+// planTeamOwner plans to add a team to the repository. The permission of a
+// team applies to all of its repositories, so a team whose permission is too
+// low cannot be granted here.
+func planTeamOwner(owner string, repoTeams []*Team, orgTeams []*Team, o string, org string, name string) (*codeownerizer.Action, error) {
+	if !strings.EqualFold(org, owner) {
+		return nil, fmt.Errorf("%s does not belong to %s", o, owner)
+	}
+	var team *Team
+	for _, t := range orgTeams {
+		if strings.EqualFold(t.Name, name) {
+			team = t
+		}
+	}
+	if team == nil {
+		return nil, fmt.Errorf("team %s does not exist", o)
+	}
+	if level(team.codePermission()) < level("write") {
+		return nil, fmt.Errorf("%s has the %s permission on all of its repositories and cannot approve", o, team.codePermission())
+	}
+	for _, t := range repoTeams {
+		if t.ID == team.ID {
+			return nil, nil
+		}
+	}
+	return &codeownerizer.Action{
+		Type:       codeownerizer.ActionGrantTeam,
+		Owner:      o,
+		Principal:  team.Name,
+		Permission: team.codePermission(),
+	}, nil
+}
+
+// planUserOwner plans to add a user as a collaborator when the user's
+// permission, directly or through teams, is below the target.
+func planUserOwner(ctx context.Context, c *Client, owner string, repo string, o string, opts *Options) (*codeownerizer.Action, error) {
+	login := strings.TrimPrefix(o, "@")
+	if _, err := c.GetUser(ctx, login); err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("user %s does not exist", o)
+		}
+		return nil, err
+	}
+	permission, err := c.GetCollaboratorPermission(ctx, owner, repo, login)
+	if err != nil {
+		return nil, err
+	}
+	if level(permission) >= level(opts.permission()) {
+		return nil, nil
+	}
+	if permission == "none" {
+		permission = ""
+	}
+	return &codeownerizer.Action{
+		Type:          codeownerizer.ActionGrantUser,
+		Owner:         o,
+		Principal:     login,
+		Permission:    opts.permission(),
+		OldPermission: permission,
+	}, nil
+}
+
+// annotateRules sets the rules that list the owner of each action.
+func annotateRules(actions []codeownerizer.Action, file *File) []codeownerizer.Action {
+	var rules []forge.Rule
+	for _, rule := range file.Rules {
+		rules = append(rules, forge.Rule{Pattern: rule.Pattern, LineNumber: rule.LineNumber, Owners: rule.Owners})
+	}
+	return forge.AnnotateRules(actions, rules)
+}
+
+// Apply applies the actions planned by Plan. Failing to apply an action is
+// logged and does not stop the others from being applied.
+func Apply(ctx context.Context, c *Client, owner string, repo string, actions []codeownerizer.Action) {
+	forge.Apply(actions, func(action codeownerizer.Action) error {
+		switch action.Type {
+		case codeownerizer.ActionGrantUser:
+			return c.AddCollaborator(ctx, owner, repo, action.Principal, action.Permission)
+		case codeownerizer.ActionGrantTeam:
+			return c.AddRepoTeam(ctx, owner, repo, action.Principal)
+		default:
+			return fmt.Errorf("unknown action type: %s", action.Type)
+		}
+	}, func(action codeownerizer.Action) string {
+		return fmt.Sprintf("%s was added to the repo with the %s permission.", action.Owner, action.Permission)
+	})
+}
+
+// Reconcile fetches the CODEOWNERS file of the repository on its default
+// branch and gives its owners the permission.
+func Reconcile(ctx context.Context, c *Client, owner string, repo string, opts *Options) error {
+	file, err := FetchCodeownersFile(ctx, c, owner, repo, "")
+	if err != nil {
+		return err
+	}
+	actions, err := Plan(ctx, c, owner, repo, file, opts)
+	if err != nil {
+		return err
+	}
+	Apply(ctx, c, owner, repo, actions)
+	return nil
+}
@@ -0,0 +1,243 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/internal/forgetest"
+)
+
+// fakeGitea serves the repository org/repo of the organization org.
+type fakeGitea struct {
+	file          string
+	users         map[string]bool
+	teams         []*Team
+	members       map[string][]string
+	repoTeams     map[int64]bool
+	collaborators map[string]string
+}
+
+func newFakeGitea(t *testing.T, file string) (*fakeGitea, *Client) {
+	t.Helper()
+	f := &fakeGitea{
+		file:  file,
+		users: map[string]bool{"user1": true, "user2": true, "user3": true},
+		teams: []*Team{
+			{ID: 1, Name: "Owners", Permission: "owner"},
+			{ID: 2, Name: "developers", Permission: "read", UnitsMap: map[string]string{"repo.code": "write"}},
+			{ID: 3, Name: "readers", Permission: "read"},
+			{ID: 4, Name: "maintainers", Permission: "admin"},
+		},
+		members:       map[string][]string{"maintainers": {"user3"}},
+		repoTeams:     map[int64]bool{1: true, 4: true},
+		collaborators: map[string]string{"user2": "read"},
+	}
+
+	server := forgetest.NewServer(t, "Authorization", "token token")
+	handle := server.Handle
+	notFound := map[string]string{"message": "The target couldn't be found."}
+	// The instance caps pages at two items, below the requested limit. The
+	// organization teams announce their count, while the repository teams
+	// end with an empty page.
+	const maxResponseItems = 2
+	handleList := func(pattern string, totalCount bool, items func() []*Team) {
+		server.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("limit") != strconv.Itoa(pageSize) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			all := items()
+			start := min((page-1)*maxResponseItems, len(all))
+			end := min(start+maxResponseItems, len(all))
+			if totalCount {
+				w.Header().Set("X-Total-Count", strconv.Itoa(len(all)))
+			}
+			_ = json.NewEncoder(w).Encode(all[start:end])
+		})
+	}
+
+	handle("GET /api/v1/repos/org/repo/raw/{path...}", func(r *http.Request) (any, int) {
+		if r.PathValue("path") != ".gitea/CODEOWNERS" {
+			return notFound, http.StatusNotFound
+		}
+		return f.file, http.StatusOK
+	})
+	handleList("GET /api/v1/repos/org/repo/teams", false, func() []*Team {
+		teams := []*Team{}
+		for _, team := range f.teams {
+			if f.repoTeams[team.ID] {
+				teams = append(teams, team)
+			}
+		}
+		return teams
+	})
+	handle("PUT /api/v1/repos/org/repo/teams/{team}", func(r *http.Request) (any, int) {
+		for _, team := range f.teams {
+			if team.Name == r.PathValue("team") {
+				f.repoTeams[team.ID] = true
+				return nil, http.StatusNoContent
+			}
+		}
+		return notFound, http.StatusNotFound
+	})
+	handleList("GET /api/v1/orgs/org/teams", true, func() []*Team {
+		return f.teams
+	})
+	handle("GET /api/v1/users/{user}", func(r *http.Request) (any, int) {
+		if !f.users[r.PathValue("user")] {
+			return notFound, http.StatusNotFound
+		}
+		return &User{Login: r.PathValue("user")}, http.StatusOK
+	})
+	handle("GET /api/v1/repos/org/repo/collaborators/{user}/permission", func(r *http.Request) (any, int) {
+		login := r.PathValue("user")
+		permission := "none"
+		if p, ok := f.collaborators[login]; ok {
+			permission = p
+		}
+		for _, team := range f.teams {
+			for _, member := range f.members[team.Name] {
+				if member == login && f.repoTeams[team.ID] && level(team.Permission) > level(permission) {
+					permission = team.Permission
+				}
+			}
+		}
+		return map[string]string{"permission": permission}, http.StatusOK
+	})
+	handle("PUT /api/v1/repos/org/repo/collaborators/{user}", func(r *http.Request) (any, int) {
+		var body struct {
+			Permission string `json:"permission"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.collaborators[r.PathValue("user")] = body.Permission
+		return nil, http.StatusNoContent
+	})
+
+	return f, NewClient(server.URL, "token", server.Client())
+}
+
+func TestReconcile(t *testing.T) {
+	content := `# Gitea patterns are regular expressions.
+.*\.go$ @user1 @org/developers @org/Owners
+!vendor/.* @user2
+docs/.* @user3 @org/readers @missing @other/team
+`
+	f, c := newFakeGitea(t, content)
+	ctx := context.Background()
+
+	file, err := FetchCodeownersFile(ctx, c, "org", "repo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(".gitea/CODEOWNERS", file.Path); diff != "" {
+		t.Errorf("unexpected path\n%s", diff)
+	}
+
+	actions, err := Plan(ctx, c, "org", "repo", file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []codeownerizer.Action{
+		{
+			Type:       codeownerizer.ActionGrantUser,
+			Owner:      "@user1",
+			Principal:  "user1",
+			Permission: "write",
+			Rules:      []codeownerizer.RuleRef{{Pattern: `.*\.go$`, LineNumber: 2}},
+		},
+		{
+			Type:       codeownerizer.ActionGrantTeam,
+			Owner:      "@org/developers",
+			Principal:  "developers",
+			Permission: "write",
+			Rules:      []codeownerizer.RuleRef{{Pattern: `.*\.go$`, LineNumber: 2}},
+		},
+		{
+			Type:          codeownerizer.ActionGrantUser,
+			Owner:         "@user2",
+			Principal:     "user2",
+			Permission:    "write",
+			OldPermission: "read",
+			Rules:         []codeownerizer.RuleRef{{Pattern: "vendor/.*", LineNumber: 3}},
+		},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+
+	if err := Reconcile(ctx, c, "org", "repo", nil); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"user1": "write", "user2": "write"}, f.collaborators); diff != "" {
+		t.Errorf("unexpected collaborators\n%s", diff)
+	}
+	if diff := cmp.Diff(map[int64]bool{1: true, 2: true, 4: true}, f.repoTeams); diff != "" {
+		t.Errorf("unexpected repository teams\n%s", diff)
+	}
+
+	actions, err = Plan(ctx, c, "org", "repo", file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no actions after reconciling, got %v", actions)
+	}
+}
+
+func TestPlanInvalidPermission(t *testing.T) {
+	_, c := newFakeGitea(t, "")
+	_, err := Plan(context.Background(), c, "org", "repo", &File{}, &Options{Permission: "owner"})
+	if err == nil || err.Error() != "invalid permission: owner, use write or admin" {
+		t.Errorf("expected an invalid permission error, got %v", err)
+	}
+}
+
+func TestListTeams(t *testing.T) {
+	f, c := newFakeGitea(t, "")
+	f.repoTeams[3] = true
+	ctx := context.Background()
+
+	names := func(teams []*Team) []string {
+		var names []string
+		for _, team := range teams {
+			names = append(names, team.Name)
+		}
+		return names
+	}
+	orgTeams, err := c.ListOrgTeams(ctx, "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"Owners", "developers", "readers", "maintainers"}, names(orgTeams)); diff != "" {
+		t.Errorf("unexpected organization teams\n%s", diff)
+	}
+	repoTeams, err := c.ListRepoTeams(ctx, "org", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"Owners", "readers", "maintainers"}, names(repoTeams)); diff != "" {
+		t.Errorf("unexpected repository teams\n%s", diff)
+	}
+}
+
+func TestParse(t *testing.T) {
+	file, err := Parse(strings.NewReader("# comment\n\n!docs/.* @user1 @org/team # trailing\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{{Pattern: "docs/.*", Negated: true, Owners: []string{"@user1", "@org/team"}, LineNumber: 3}}
+	if diff := cmp.Diff(want, file.Rules); diff != "" {
+		t.Errorf("unexpected rules\n%s", diff)
+	}
+
+	if _, err := Parse(strings.NewReader("* user1\n")); err == nil || err.Error() != "line 1: invalid owner: user1" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grezar/codeownerizer/internal/forge"
+)
+
+// AccessLevel is the role of a member in a GitLab project or group.
+type AccessLevel int
+
+const (
+	GuestAccess      AccessLevel = 10
+	ReporterAccess   AccessLevel = 20
+	DeveloperAccess  AccessLevel = 30
+	MaintainerAccess AccessLevel = 40
+	OwnerAccess      AccessLevel = 50
+)
+
+// roleAccessLevels maps the roles of role owners to their access levels.
+var roleAccessLevels = map[string]AccessLevel{
+	"developer":  DeveloperAccess,
+	"maintainer": MaintainerAccess,
+	"owner":      OwnerAccess,
+}
+
+func (l AccessLevel) String() string {
+	switch l {
+	case GuestAccess:
+		return "guest"
+	case ReporterAccess:
+		return "reporter"
+	case DeveloperAccess:
+		return "developer"
+	case MaintainerAccess:
+		return "maintainer"
+	case OwnerAccess:
+		return "owner"
+	case 0:
+		return ""
+	default:
+		return strconv.Itoa(int(l))
+	}
+}
+
+// ParseAccessLevel parses the name of a role that can approve merge requests.
+func ParseAccessLevel(name string) (AccessLevel, error) {
+	level, ok := roleAccessLevels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown access level: %s", name)
+	}
+	return level, nil
+}
+
+// Project is a GitLab project.
+type Project struct {
+	ID                int64          `json:"id"`
+	PathWithNamespace string         `json:"path_with_namespace"`
+	DefaultBranch     string         `json:"default_branch"`
+	SharedWithGroups  []*SharedGroup `json:"shared_with_groups"`
+}
+
+// SharedGroup is a group a project is shared with.
+type SharedGroup struct {
+	GroupID          int64       `json:"group_id"`
+	GroupFullPath    string      `json:"group_full_path"`
+	GroupAccessLevel AccessLevel `json:"group_access_level"`
+}
+
+// Member is a member of a project, directly or through its groups.
+type Member struct {
+	ID          int64       `json:"id"`
+	Username    string      `json:"username"`
+	State       string      `json:"state"`
+	AccessLevel AccessLevel `json:"access_level"`
+}
+
+// User is a GitLab user.
+type User struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	State       string `json:"state"`
+	Email       string `json:"email"`
+	PublicEmail string `json:"public_email"`
+}
+
+// Group is a GitLab group.
+type Group struct {
+	ID       int64  `json:"id"`
+	FullPath string `json:"full_path"`
+}
+
+// ErrorResponse is an error returned by the GitLab API.
+type ErrorResponse = forge.ErrorResponse
+
+// IsNotFound reports whether the error is a 404 response.
+func IsNotFound(err error) bool {
+	return forge.IsNotFound(err)
+}
+
+// Client is a client of the GitLab REST API v4.
+type Client struct {
+	api *forge.Client
+}
+
+// NewClient returns a client of the GitLab instance at the URL, such as
+// https://gitlab.com, authenticating with the personal, group or project
+// access token. The HTTP client defaults to http.DefaultClient.
+func NewClient(baseURL string, token string, httpClient *http.Client) *Client {
+	return &Client{api: &forge.Client{
+		API:        "GitLab",
+		BaseURL:    strings.TrimSuffix(baseURL, "/") + "/api/v4",
+		AuthHeader: "PRIVATE-TOKEN",
+		AuthValue:  token,
+		HTTPClient: httpClient,
+	}}
+}
+
+// do sends a request to the path, which must be escaped, and decodes the
+// response into out unless it is nil.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any) (*http.Response, error) {
+	return c.api.Do(ctx, method, path, query, body, out)
+}
+
+// list pages through a list endpoint, a hundred items at a time.
+func list[T any](ctx context.Context, c *Client, path string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", "100")
+	all := []T{}
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+		var items []T
+		resp, err := c.do(ctx, http.MethodGet, path, query, nil, &items)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		page = resp.Header.Get("X-Next-Page")
+	}
+	return all, nil
+}
+
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+// GetProject gets the project by its ID or its path with namespace.
+func (c *Client) GetProject(ctx context.Context, project string) (*Project, error) {
+	var p Project
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project), nil, nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListAllMembers lists the members of the project, including those inherited
+// from its ancestor groups, with their highest access level.
+func (c *Client) ListAllMembers(ctx context.Context, project string) ([]*Member, error) {
+	return list[*Member](ctx, c, projectPath(project)+"/members/all", nil)
+}
+
+// AddMember adds the user to the project with the access level, or changes
+// the access level of a direct member.
+func (c *Client) AddMember(ctx context.Context, project string, userID int64, level AccessLevel) error {
+	body := map[string]any{"user_id": userID, "access_level": level}
+	_, err := c.do(ctx, http.MethodPost, projectPath(project)+"/members", nil, body, nil)
+	var errorResponse *ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.StatusCode == http.StatusConflict {
+		path := fmt.Sprintf("%s/members/%d", projectPath(project), userID)
+		_, err = c.do(ctx, http.MethodPut, path, nil, map[string]any{"access_level": level}, nil)
+	}
+	return err
+}
+
+// ShareWithGroup shares the project with the group at the access level.
+func (c *Client) ShareWithGroup(ctx context.Context, project string, groupID int64, level AccessLevel) error {
+	body := map[string]any{"group_id": groupID, "group_access": level}
+	_, err := c.do(ctx, http.MethodPost, projectPath(project)+"/share", nil, body, nil)
+	return err
+}
+
+// UnshareWithGroup stops sharing the project with the group.
+func (c *Client) UnshareWithGroup(ctx context.Context, project string, groupID int64) error {
+	path := fmt.Sprintf("%s/share/%d", projectPath(project), groupID)
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+	return err
+}
+
+// FindUser finds the user with the username, returning nil when there is none.
+func (c *Client) FindUser(ctx context.Context, username string) (*User, error) {
+	var users []*User
+	if _, err := c.do(ctx, http.MethodGet, "/users", url.Values{"username": {username}}, nil, &users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+// SearchUsersByEmail finds the users whose email is the given one. Only
+// administrators see the private emails of other users.
+func (c *Client) SearchUsersByEmail(ctx context.Context, email string) ([]*User, error) {
+	users, err := list[*User](ctx, c, "/users", url.Values{"search": {email}})
+	if err != nil {
+		return nil, err
+	}
+	var matched []*User
+	for _, user := range users {
+		if strings.EqualFold(user.Email, email) || strings.EqualFold(user.PublicEmail, email) {
+			matched = append(matched, user)
+		}
+	}
+	return matched, nil
+}
+
+// GetGroup gets the group by its full path.
+func (c *Client) GetGroup(ctx context.Context, fullPath string) (*Group, error) {
+	var g Group
+	if _, err := c.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(fullPath), nil, nil, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListAllGroupMembers lists the members of the group, including those
+// inherited from its ancestor groups.
+func (c *Client) ListAllGroupMembers(ctx context.Context, fullPath string) ([]*Member, error) {
+	return list[*Member](ctx, c, "/groups/"+url.PathEscape(fullPath)+"/members/all", nil)
+}
+
+// GetRawFile gets the content of a file at the ref. An empty ref reads the
+// default branch.
+func (c *Client) GetRawFile(ctx context.Context, project string, path string, ref string) ([]byte, error) {
+	query := url.Values{}
+	if ref != "" {
+		query.Set("ref", ref)
+	}
+	var content []byte
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project)+"/repository/files/"+url.PathEscape(path)+"/raw", query, nil, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
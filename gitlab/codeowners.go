@@ -0,0 +1,204 @@
+package gitlab
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UserOwner is an owner written as @name. GitLab also resolves it to a
+	// top-level group when no user has the name.
+	UserOwner = "user"
+	// GroupOwner is an owner written as @group/subgroup.
+	GroupOwner = "group"
+	// RoleOwner is an owner written as @@developer, @@maintainer or @@owner,
+	// which stands for the project members with the role.
+	RoleOwner = "role"
+	// EmailOwner is an owner written as an email address.
+	EmailOwner = "email"
+)
+
+// Owner is an owner of a rule in a GitLab CODEOWNERS file.
+type Owner struct {
+	// Value is the owner without its @ or @@ prefix.
+	Value string
+	Type  string
+}
+
+func (o Owner) String() string {
+	switch o.Type {
+	case RoleOwner:
+		return "@@" + o.Value
+	case EmailOwner:
+		return o.Value
+	default:
+		return "@" + o.Value
+	}
+}
+
+// Section is a section of a GitLab CODEOWNERS file. Rules before the first
+// section header belong to a section without a name.
+type Section struct {
+	Name string
+	// Optional is set for ^[Section] headers, whose approval is not required.
+	Optional bool
+	// Approvals is the number of approvals the section requires.
+	Approvals int
+	// DefaultOwners own the rules of the section that list no owner.
+	DefaultOwners []Owner
+	LineNumber    int
+	Rules         []Rule
+}
+
+// Rule is a pattern and the owners of the files matching it.
+type Rule struct {
+	Pattern string
+	// Owners are the owners listed on the line, or the default owners of the
+	// section when the line lists none.
+	Owners     []Owner
+	LineNumber int
+}
+
+// File is a parsed GitLab CODEOWNERS file.
+type File struct {
+	// Path is where the file was found in the repository.
+	Path     string
+	Sections []*Section
+}
+
+// Rules lists the rules of all sections.
+func (f *File) Rules() []Rule {
+	var rules []Rule
+	for _, section := range f.Sections {
+		rules = append(rules, section.Rules...)
+	}
+	return rules
+}
+
+// sectionHeader matches [Section], ^[Section] and [Section][2], optionally
+// followed by the default owners.
+var sectionHeader = regexp.MustCompile(`^(\^)?\[([^\]]+)\](?:\[(\d+)\])?\s*(.*)$`)
+
+// Parse parses a GitLab CODEOWNERS file.
+func Parse(r io.Reader) (*File, error) {
+	current := &Section{Approvals: 1}
+	file := &File{Sections: []*Section{current}}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			owners, err := parseOwners(strings.Fields(m[4]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			current = &Section{
+				Name:          m[2],
+				Optional:      m[1] != "",
+				Approvals:     1,
+				DefaultOwners: owners,
+				LineNumber:    lineNumber,
+			}
+			if m[1] != "" {
+				current.Approvals = 0
+			}
+			if m[3] != "" {
+				current.Approvals, _ = strconv.Atoi(m[3])
+			}
+			file.Sections = append(file.Sections, current)
+			continue
+		}
+
+		pattern, rest := splitPattern(line)
+		owners, err := parseOwners(strings.Fields(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if len(owners) == 0 {
+			owners = current.DefaultOwners
+		}
+		current.Rules = append(current.Rules, Rule{
+			Pattern:    pattern,
+			Owners:     owners,
+			LineNumber: lineNumber,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// ParseBytes parses the content of a GitLab CODEOWNERS file.
+func ParseBytes(content []byte) (*File, error) {
+	return Parse(bytes.NewReader(content))
+}
+
+// stripComment removes a comment starting with an unescaped #.
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitPattern splits a rule into its pattern, which ends at the first
+// unescaped whitespace, and the rest of the line.
+func splitPattern(line string) (string, string) {
+	var pattern strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			pattern.WriteByte(line[i])
+		case c == ' ' || c == '\t':
+			return pattern.String(), line[i:]
+		default:
+			pattern.WriteByte(c)
+		}
+	}
+	return pattern.String(), ""
+}
+
+func parseOwners(fields []string) ([]Owner, error) {
+	var owners []Owner
+	for _, field := range fields {
+		var owner Owner
+		switch {
+		case strings.HasPrefix(field, "@@"):
+			owner = Owner{Value: strings.ToLower(field[2:]), Type: RoleOwner}
+			if _, ok := roleAccessLevels[owner.Value]; !ok {
+				return nil, fmt.Errorf("invalid role: %s", field)
+			}
+		case strings.HasPrefix(field, "@") && strings.Contains(field, "/"):
+			owner = Owner{Value: field[1:], Type: GroupOwner}
+		case strings.HasPrefix(field, "@"):
+			owner = Owner{Value: field[1:], Type: UserOwner}
+		case strings.Contains(field, "@"):
+			owner = Owner{Value: field, Type: EmailOwner}
+		default:
+			return nil, fmt.Errorf("invalid owner: %s", field)
+		}
+		if owner.Value == "" {
+			return nil, fmt.Errorf("invalid owner: %s", field)
+		}
+		owners = append(owners, owner)
+	}
+	return owners, nil
+}
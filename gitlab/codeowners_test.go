@@ -0,0 +1,76 @@
+package gitlab
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/CODEOWNERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writers := []Owner{{Value: "docs-team/writers", Type: GroupOwner}}
+	maintainer := []Owner{{Value: "maintainer", Type: RoleOwner}}
+	admin := []Owner{{Value: "admin", Type: UserOwner}}
+	want := []*Section{
+		{
+			Approvals: 1,
+			Rules:     []Rule{{Pattern: "*", Owners: admin, LineNumber: 2}},
+		},
+		{
+			Name:          "Docs",
+			Approvals:     2,
+			DefaultOwners: writers,
+			LineNumber:    4,
+			Rules: []Rule{
+				{Pattern: "/docs/", Owners: writers, LineNumber: 5},
+				{Pattern: "/docs/api/", Owners: []Owner{{Value: "api-writer", Type: UserOwner}}, LineNumber: 6},
+			},
+		},
+		{
+			Name:          "Optional",
+			Optional:      true,
+			DefaultOwners: maintainer,
+			LineNumber:    8,
+			Rules: []Rule{
+				{Pattern: "*.md", Owners: []Owner{{Value: "reviewer", Type: UserOwner}, {Value: "jane@example.com", Type: EmailOwner}}, LineNumber: 9},
+			},
+		},
+		{
+			Name:       "Ruby",
+			Approvals:  1,
+			LineNumber: 11,
+			Rules: []Rule{
+				{Pattern: "*.rb", Owners: []Owner{{Value: "developer", Type: RoleOwner}, {Value: "ruby/core", Type: GroupOwner}}, LineNumber: 12},
+				{Pattern: "/path with spaces/", Owners: admin, LineNumber: 13},
+				{Pattern: "#notes", Owners: admin, LineNumber: 14},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, file.Sections); diff != "" {
+		t.Errorf("unexpected sections\n%s", diff)
+	}
+}
+
+func TestParseInvalidOwner(t *testing.T) {
+	for content, want := range map[string]string{
+		"* admin\n":           "line 1: invalid owner: admin",
+		"[Section] @@guest\n": "line 1: invalid role: @@guest",
+	} {
+		_, err := Parse(strings.NewReader(content))
+		if err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) returned %v, want %s", content, err, want)
+		}
+	}
+}
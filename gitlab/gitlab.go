@@ -0,0 +1,271 @@
+// Package gitlab grants the code owners of GitLab projects the access they need
+// to approve merge requests. It parses the GitLab flavor of CODEOWNERS, with
+// sections, optional sections, approval counts and role owners, and plans the
+// same actions as the codeownerizer package for GitHub.
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/internal/forge"
+)
+
+// codeownersPaths are where GitLab looks for the CODEOWNERS file, in order.
+var codeownersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// Options configures which grants are made on GitLab.
+type Options struct {
+	// AccessLevel is the access level granted to code owners. Defaults to
+	// Developer, the lowest level that can approve merge requests.
+	AccessLevel AccessLevel
+
+	// IgnoredOwners are owners, as written in CODEOWNERS, that are never
+	// granted.
+	IgnoredOwners []string
+}
+
+func (o *Options) accessLevel() AccessLevel {
+	if o.AccessLevel == 0 {
+		return DeveloperAccess
+	}
+	return o.AccessLevel
+}
+
+func (o *Options) isIgnored(owner string) bool {
+	return forge.IsIgnored(o.IgnoredOwners, owner)
+}
+
+// FetchCodeownersFile fetches and parses the CODEOWNERS file of the project at
+// the ref, looking in the same locations as GitLab.
+func FetchCodeownersFile(ctx context.Context, c *Client, project string, ref string) (*File, error) {
+	for _, path := range codeownersPaths {
+		content, err := c.GetRawFile(ctx, project, path, ref)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		file, err := ParseBytes(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		file.Path = path
+		return file, nil
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file was found in %s", project)
+}
+
+// Plan computes the actions that give the owners of the rules the access
+// level, without changing anything. Users are added to the project as members
+// and groups are shared with it. The members of a group the project belongs to
+// are added to it one by one, since the project cannot be shared with such a
+// group. Role owners need nothing. Owners that cannot
+// be checked are logged and left out of the plan.
+func Plan(ctx context.Context, c *Client, project string, file *File, opts *Options) ([]codeownerizer.Action, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	p, err := c.GetProject(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	members, err := c.ListAllMembers(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]AccessLevel, len(members))
+	for _, member := range members {
+		levels[strings.ToLower(member.Username)] = member.AccessLevel
+	}
+
+	var actions []codeownerizer.Action
+	planned := make(map[string]bool)
+	granted := make(map[string]bool)
+	for _, rule := range file.Rules() {
+		for _, owner := range rule.Owners {
+			if planned[owner.String()] || opts.isIgnored(owner.String()) {
+				continue
+			}
+			planned[owner.String()] = true
+
+			owned, err := planOwner(ctx, c, p, levels, owner, opts)
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+			for _, action := range owned {
+				// A user may be listed both directly and through a group.
+				key := action.Type + " " + action.Principal
+				if granted[key] {
+					continue
+				}
+				granted[key] = true
+				actions = append(actions, action)
+			}
+		}
+	}
+	return annotateRules(actions, file), nil
+}
+
+func planOwner(ctx context.Context, c *Client, p *Project, levels map[string]AccessLevel, owner Owner, opts *Options) ([]codeownerizer.Action, error) {
+	switch owner.Type {
+	case RoleOwner:
+		return nil, nil
+	case GroupOwner:
+		return planGroupOwner(ctx, c, p, levels, owner, opts)
+	case EmailOwner:
+		users, err := c.SearchUsersByEmail(ctx, owner.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) != 1 {
+			return nil, &codeownerizer.EmailResolutionError{Email: owner.Value, Matches: len(users)}
+		}
+		return planUserOwner(levels, owner, users[0].Username, opts), nil
+	case UserOwner:
+		user, err := c.FindUser(ctx, owner.Value)
+		if err != nil {
+			return nil, err
+		}
+		// @name refers to a top-level group when no user has the name.
+		if user == nil {
+			return planGroupOwner(ctx, c, p, levels, owner, opts)
+		}
+		return planUserOwner(levels, owner, user.Username, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown owner type: %s", owner.Type)
+	}
+}
+
+func planUserOwner(levels map[string]AccessLevel, owner Owner, username string, opts *Options) []codeownerizer.Action {
+	level := levels[strings.ToLower(username)]
+	if level >= opts.accessLevel() {
+		return nil
+	}
+	return []codeownerizer.Action{{
+		Type:          codeownerizer.ActionGrantUser,
+		Owner:         owner.String(),
+		Principal:     username,
+		Permission:    opts.accessLevel().String(),
+		OldPermission: level.String(),
+	}}
+}
+
+// planGroupOwner plans to share the project with a group, unless it is shared
+// with it at the access level. A project cannot be shared with a group it
+// belongs to, whose members inherit their group role instead, so the members
+// below the access level are added to the project themselves.
+func planGroupOwner(ctx context.Context, c *Client, p *Project, levels map[string]AccessLevel, owner Owner, opts *Options) ([]codeownerizer.Action, error) {
+	if strings.HasPrefix(strings.ToLower(p.PathWithNamespace), strings.ToLower(owner.Value)+"/") {
+		members, err := c.ListAllGroupMembers(ctx, owner.Value)
+		if err != nil {
+			return nil, err
+		}
+		var actions []codeownerizer.Action
+		for _, member := range members {
+			actions = append(actions, planUserOwner(levels, owner, member.Username, opts)...)
+		}
+		return actions, nil
+	}
+	var level AccessLevel
+	for _, shared := range p.SharedWithGroups {
+		if strings.EqualFold(shared.GroupFullPath, owner.Value) {
+			level = shared.GroupAccessLevel
+		}
+	}
+	if level >= opts.accessLevel() {
+		return nil, nil
+	}
+	if _, err := c.GetGroup(ctx, owner.Value); err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("no user or group named %s was found", owner.Value)
+		}
+		return nil, err
+	}
+	return []codeownerizer.Action{{
+		Type:          codeownerizer.ActionGrantTeam,
+		Owner:         owner.String(),
+		Principal:     owner.Value,
+		Permission:    opts.accessLevel().String(),
+		OldPermission: level.String(),
+	}}, nil
+}
+
+// annotateRules sets the rules that list the owner of each action.
+func annotateRules(actions []codeownerizer.Action, file *File) []codeownerizer.Action {
+	var rules []forge.Rule
+	for _, rule := range file.Rules() {
+		owners := make([]string, len(rule.Owners))
+		for i, owner := range rule.Owners {
+			owners[i] = owner.String()
+		}
+		rules = append(rules, forge.Rule{Pattern: rule.Pattern, LineNumber: rule.LineNumber, Owners: owners})
+	}
+	return forge.AnnotateRules(actions, rules)
+}
+
+// Apply applies the actions planned by Plan. Failing to apply an action is
+// logged and does not stop the others from being applied.
+func Apply(ctx context.Context, c *Client, project string, actions []codeownerizer.Action) {
+	forge.Apply(actions, func(action codeownerizer.Action) error {
+		return applyAction(ctx, c, project, action)
+	}, func(action codeownerizer.Action) string {
+		if action.Type == codeownerizer.ActionGrantTeam {
+			return fmt.Sprintf("The project was shared with %s with the %s role.", action.Owner, action.Permission)
+		}
+		return fmt.Sprintf("%s was added to the project with the %s role.", action.Owner, action.Permission)
+	})
+}
+
+func applyAction(ctx context.Context, c *Client, project string, action codeownerizer.Action) error {
+	level, err := ParseAccessLevel(action.Permission)
+	if err != nil {
+		return err
+	}
+	switch action.Type {
+	case codeownerizer.ActionGrantUser:
+		user, err := c.FindUser(ctx, action.Principal)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("no user named %s was found", action.Principal)
+		}
+		return c.AddMember(ctx, project, user.ID, level)
+	case codeownerizer.ActionGrantTeam:
+		group, err := c.GetGroup(ctx, action.Principal)
+		if err != nil {
+			return err
+		}
+		// A share cannot be changed, so a share at a lower level is replaced.
+		if action.OldPermission != "" {
+			if err := c.UnshareWithGroup(ctx, project, group.ID); err != nil {
+				return err
+			}
+		}
+		return c.ShareWithGroup(ctx, project, group.ID, level)
+	default:
+		return fmt.Errorf("unknown action type: %s", action.Type)
+	}
+}
+
+// Reconcile fetches the CODEOWNERS file of the project on its default branch
+// and gives its owners the access level.
+func Reconcile(ctx context.Context, c *Client, project string, opts *Options) error {
+	file, err := FetchCodeownersFile(ctx, c, project, "")
+	if err != nil {
+		return err
+	}
+	actions, err := Plan(ctx, c, project, file, opts)
+	if err != nil {
+		return err
+	}
+	Apply(ctx, c, project, actions)
+	return nil
+}
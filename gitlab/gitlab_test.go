@@ -0,0 +1,229 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/grezar/codeownerizer"
+	"github.com/grezar/codeownerizer/internal/forgetest"
+)
+
+// fakeGitLab serves the project group/project, whose CODEOWNERS file is in
+// .gitlab. The members of the group are members of the project too.
+type fakeGitLab struct {
+	file         string
+	users        map[string]*User
+	groups       map[string]*Group
+	groupMembers map[string][]int64
+	members      map[int64]AccessLevel
+	shares       map[int64]AccessLevel
+}
+
+func newFakeGitLab(t *testing.T, file string) (*fakeGitLab, *Client) {
+	t.Helper()
+	f := &fakeGitLab{
+		file: file,
+		users: map[string]*User{
+			"admin":    {ID: 1, Username: "admin"},
+			"reviewer": {ID: 2, Username: "reviewer"},
+			"jane":     {ID: 3, Username: "jane", PublicEmail: "jane@example.com"},
+			"guest":    {ID: 4, Username: "guest"},
+		},
+		groups: map[string]*Group{
+			"group":             {ID: 10, FullPath: "group"},
+			"docs-team/writers": {ID: 11, FullPath: "docs-team/writers"},
+			"ruby/core":         {ID: 12, FullPath: "ruby/core"},
+		},
+		groupMembers: map[string][]int64{"group": {1, 2, 4}},
+		members:      map[int64]AccessLevel{1: MaintainerAccess, 2: ReporterAccess, 4: GuestAccess},
+		shares:       map[int64]AccessLevel{12: ReporterAccess},
+	}
+
+	server := forgetest.NewServer(t, "PRIVATE-TOKEN", "token")
+	project := "/api/v4/projects/group%2Fproject"
+	handle := server.Handle
+	notFound := map[string]string{"message": "404 Not found"}
+
+	handle("GET "+project+"/repository/files/{path}/raw", func(r *http.Request) (any, int) {
+		if r.PathValue("path") != ".gitlab/CODEOWNERS" {
+			return notFound, http.StatusNotFound
+		}
+		return f.file, http.StatusOK
+	})
+	handle("GET "+project, func(r *http.Request) (any, int) {
+		p := &Project{ID: 1, PathWithNamespace: "group/project", DefaultBranch: "main"}
+		for _, group := range f.groups {
+			if level, ok := f.shares[group.ID]; ok {
+				p.SharedWithGroups = append(p.SharedWithGroups, &SharedGroup{GroupID: group.ID, GroupFullPath: group.FullPath, GroupAccessLevel: level})
+			}
+		}
+		return p, http.StatusOK
+	})
+	handle("GET "+project+"/members/all", func(r *http.Request) (any, int) {
+		members := []*Member{}
+		for _, user := range f.users {
+			if level, ok := f.members[user.ID]; ok {
+				members = append(members, &Member{ID: user.ID, Username: user.Username, AccessLevel: level})
+			}
+		}
+		return members, http.StatusOK
+	})
+	handle("POST "+project+"/members", func(r *http.Request) (any, int) {
+		var body struct {
+			UserID      int64       `json:"user_id"`
+			AccessLevel AccessLevel `json:"access_level"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := f.members[body.UserID]; ok {
+			return map[string]string{"message": "Member already exists"}, http.StatusConflict
+		}
+		f.members[body.UserID] = body.AccessLevel
+		return nil, http.StatusCreated
+	})
+	handle("PUT "+project+"/members/{id}", func(r *http.Request) (any, int) {
+		var body struct {
+			AccessLevel AccessLevel `json:"access_level"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		f.members[id] = body.AccessLevel
+		return nil, http.StatusOK
+	})
+	handle("POST "+project+"/share", func(r *http.Request) (any, int) {
+		var body struct {
+			GroupID     int64       `json:"group_id"`
+			GroupAccess AccessLevel `json:"group_access"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if _, ok := f.shares[body.GroupID]; ok {
+			return map[string]string{"message": "already shared"}, http.StatusConflict
+		}
+		f.shares[body.GroupID] = body.GroupAccess
+		return nil, http.StatusCreated
+	})
+	handle("DELETE "+project+"/share/{id}", func(r *http.Request) (any, int) {
+		id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		delete(f.shares, id)
+		return nil, http.StatusNoContent
+	})
+	handle("GET /api/v4/users", func(r *http.Request) (any, int) {
+		users := []*User{}
+		for _, user := range f.users {
+			if q := r.URL.Query(); user.Username == q.Get("username") || (q.Get("search") != "" && user.PublicEmail == q.Get("search")) {
+				users = append(users, user)
+			}
+		}
+		return users, http.StatusOK
+	})
+	handle("GET /api/v4/groups/{path}/members/all", func(r *http.Request) (any, int) {
+		members := []*Member{}
+		for _, user := range f.users {
+			if slices.Contains(f.groupMembers[r.PathValue("path")], user.ID) {
+				members = append(members, &Member{ID: user.ID, Username: user.Username, AccessLevel: f.members[user.ID]})
+			}
+		}
+		return members, http.StatusOK
+	})
+	handle("GET /api/v4/groups/{path}", func(r *http.Request) (any, int) {
+		group, ok := f.groups[r.PathValue("path")]
+		if !ok {
+			return notFound, http.StatusNotFound
+		}
+		return group, http.StatusOK
+	})
+
+	return f, NewClient(server.URL, "token", server.Client())
+}
+
+func TestReconcile(t *testing.T) {
+	content := `* @admin @reviewer
+
+[Docs] @docs-team/writers
+/docs/
+/docs/guides/ jane@example.com @@developer
+
+^[Ruby][2]
+*.rb @group @ruby/core @missing
+`
+	f, c := newFakeGitLab(t, content)
+	ctx := context.Background()
+
+	file, err := FetchCodeownersFile(ctx, c, "group/project", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(".gitlab/CODEOWNERS", file.Path); diff != "" {
+		t.Errorf("unexpected path\n%s", diff)
+	}
+
+	actions, err := Plan(ctx, c, "group/project", file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []codeownerizer.Action{
+		{
+			Type:          codeownerizer.ActionGrantUser,
+			Owner:         "@reviewer",
+			Principal:     "reviewer",
+			Permission:    "developer",
+			OldPermission: "reporter",
+			Rules:         []codeownerizer.RuleRef{{Pattern: "*", LineNumber: 1}},
+		},
+		{
+			Type:       codeownerizer.ActionGrantTeam,
+			Owner:      "@docs-team/writers",
+			Principal:  "docs-team/writers",
+			Permission: "developer",
+			Rules:      []codeownerizer.RuleRef{{Pattern: "/docs/", LineNumber: 4}},
+		},
+		{
+			Type:       codeownerizer.ActionGrantUser,
+			Owner:      "jane@example.com",
+			Principal:  "jane",
+			Permission: "developer",
+			Rules:      []codeownerizer.RuleRef{{Pattern: "/docs/guides/", LineNumber: 5}},
+		},
+		{
+			Type:          codeownerizer.ActionGrantUser,
+			Owner:         "@group",
+			Principal:     "guest",
+			Permission:    "developer",
+			OldPermission: "guest",
+			Rules:         []codeownerizer.RuleRef{{Pattern: "*.rb", LineNumber: 8}},
+		},
+		{
+			Type:          codeownerizer.ActionGrantTeam,
+			Owner:         "@ruby/core",
+			Principal:     "ruby/core",
+			Permission:    "developer",
+			OldPermission: "reporter",
+			Rules:         []codeownerizer.RuleRef{{Pattern: "*.rb", LineNumber: 8}},
+		},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+
+	if err := Reconcile(ctx, c, "group/project", nil); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[int64]AccessLevel{1: MaintainerAccess, 2: DeveloperAccess, 3: DeveloperAccess, 4: DeveloperAccess}, f.members); diff != "" {
+		t.Errorf("unexpected members\n%s", diff)
+	}
+	if diff := cmp.Diff(map[int64]AccessLevel{11: DeveloperAccess, 12: DeveloperAccess}, f.shares); diff != "" {
+		t.Errorf("unexpected shares\n%s", diff)
+	}
+
+	actions, err = Plan(ctx, c, "group/project", file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no actions after reconciling, got %v", actions)
+	}
+}
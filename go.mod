@@ -10,6 +10,7 @@ require (
 	github.com/hmarr/codeowners v0.4.0
 	github.com/migueleliasweb/go-github-mock v1.1.0
 	golang.org/x/oauth2 v0.0.0-20220411215720-9780585627b5
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,188 @@
+package codeownerizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// DefaultGraphQLURL is the endpoint of the GitHub GraphQL API.
+const DefaultGraphQLURL = "https://api.github.com/graphql"
+
+// emailsPerQuery is the number of email searches batched in a single query.
+const emailsPerQuery = 20
+
+// graphQLPermissions maps the repository permissions of the GraphQL API to the
+// Permissions maps of the REST API.
+var graphQLPermissions = map[string][]string{
+	"ADMIN":    {"admin", "maintain", "push", "triage", "pull"},
+	"MAINTAIN": {"maintain", "push", "triage", "pull"},
+	"WRITE":    {"push", "triage", "pull"},
+	"TRIAGE":   {"triage", "pull"},
+	"READ":     {"pull"},
+}
+
+// GraphQLLister is an AccessLister using the GraphQL API. It lists
+// collaborators a hundred at a time with their permissions, and batches email
+// searches into a few queries. Custom repository roles are reported as their
+// base role. Teams are listed with the REST API.
+type GraphQLLister struct {
+	api *github.Client
+	url string
+}
+
+// NewGraphQLLister returns a GraphQLLister sending queries with the HTTP
+// client of the REST client. An empty url means DefaultGraphQLURL.
+func NewGraphQLLister(api *github.Client, url string) *GraphQLLister {
+	if url == "" {
+		url = DefaultGraphQLURL
+	}
+	return &GraphQLLister{api: api, url: url}
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// query runs the query and decodes its data into result.
+func (l *GraphQLLister) query(ctx context.Context, query string, variables map[string]any, result any) error {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.api.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := github.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		var messages []string
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("graphql: %s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+const collaboratorsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    collaborators(first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      edges { permission node { login } }
+    }
+  }
+}`
+
+func (l *GraphQLLister) ListCollaborators(ctx context.Context, org string, repo string) ([]*github.User, error) {
+	collaborators := []*github.User{}
+	variables := map[string]any{"owner": org, "name": repo}
+	for {
+		var data struct {
+			Repository struct {
+				Collaborators struct {
+					PageInfo pageInfo `json:"pageInfo"`
+					Edges    []struct {
+						Permission string `json:"permission"`
+						Node       struct {
+							Login string `json:"login"`
+						} `json:"node"`
+					} `json:"edges"`
+				} `json:"collaborators"`
+			} `json:"repository"`
+		}
+		if err := l.query(ctx, collaboratorsQuery, variables, &data); err != nil {
+			return nil, err
+		}
+		for _, edge := range data.Repository.Collaborators.Edges {
+			// The role names of the REST API are the lowercase permissions,
+			// which keeps plan states comparable between both APIs.
+			collaborators = append(collaborators, &github.User{
+				Login:       github.Ptr(edge.Node.Login),
+				RoleName:    github.Ptr(strings.ToLower(edge.Permission)),
+				Permissions: permissionsOf(edge.Permission),
+			})
+		}
+		if !data.Repository.Collaborators.PageInfo.HasNextPage {
+			return collaborators, nil
+		}
+		variables["cursor"] = data.Repository.Collaborators.PageInfo.EndCursor
+	}
+}
+
+// ListTeams lists the repository teams with the REST API. The GraphQL API has
+// no connection from a repository to its teams, and going through every team
+// of the organization costs more requests than the repository has teams.
+func (l *GraphQLLister) ListTeams(ctx context.Context, org string, repo string) ([]*github.Team, error) {
+	return ListTeams(ctx, l.api, org, repo)
+}
+
+func (l *GraphQLLister) ResolveEmails(ctx context.Context, emails []string) (map[string][]string, error) {
+	resolved := make(map[string][]string, len(emails))
+	for start := 0; start < len(emails); start += emailsPerQuery {
+		end := min(start+emailsPerQuery, len(emails))
+		batch := emails[start:end]
+
+		var params, fields []string
+		variables := map[string]any{}
+		for i, email := range batch {
+			params = append(params, fmt.Sprintf("$q%d: String!", i))
+			fields = append(fields, fmt.Sprintf("e%d: search(query: $q%d, type: USER, first: 2) { nodes { ... on User { login } } }", i, i))
+			variables[fmt.Sprintf("q%d", i)] = email + " in:email"
+		}
+		query := fmt.Sprintf("query(%s) {\n  %s\n}", strings.Join(params, ", "), strings.Join(fields, "\n  "))
+
+		var data map[string]struct {
+			Nodes []struct {
+				Login string `json:"login"`
+			} `json:"nodes"`
+		}
+		if err := l.query(ctx, query, variables, &data); err != nil {
+			return nil, err
+		}
+		for i, email := range batch {
+			logins := []string{}
+			for _, node := range data[fmt.Sprintf("e%d", i)].Nodes {
+				if node.Login != "" {
+					logins = append(logins, node.Login)
+				}
+			}
+			resolved[email] = logins
+		}
+	}
+	return resolved, nil
+}
+
+func permissionsOf(permission string) map[string]bool {
+	permissions := make(map[string]bool)
+	for _, p := range graphQLPermissions[permission] {
+		permissions[p] = true
+	}
+	return permissions
+}
@@ -0,0 +1,134 @@
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+// newGraphQLServer serves the GraphQL API at /graphql and the REST API
+// listing the repository teams of org/repo, in two pages. It counts the requests by path.
+func newGraphQLServer(t *testing.T, requests map[string]int) (*httptest.Server, *github.Client) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(req.Query, "collaborators"):
+			if req.Variables["cursor"] == nil {
+				_, _ = w.Write([]byte(`{"data":{"repository":{"collaborators":{
+					"pageInfo":{"hasNextPage":true,"endCursor":"c1"},
+					"edges":[{"permission":"WRITE","node":{"login":"user1"}}]}}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"repository":{"collaborators":{
+				"pageInfo":{"hasNextPage":false},
+				"edges":[{"permission":"READ","node":{"login":"user2"}}]}}}}`))
+		case strings.Contains(req.Query, "search"):
+			if diff := cmp.Diff(map[string]any{"q0": "user2@example.com in:email", "q1": "nobody@example.com in:email"}, req.Variables); diff != "" {
+				t.Errorf("unexpected variables\n%s", diff)
+			}
+			_, _ = w.Write([]byte(`{"data":{
+				"e0":{"nodes":[{"login":"user2"}]},
+				"e1":{"nodes":[]}}}`))
+		default:
+			t.Errorf("unexpected query\n%s", req.Query)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"unexpected query"}]}`))
+		}
+	})
+	var server *httptest.Server
+	mux.HandleFunc("GET /repos/org/repo/teams", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") != "2" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/org/repo/teams?page=2>; rel="next"`, server.URL))
+			_, _ = w.Write(mock.MustMarshal([]github.Team{
+				{Slug: github.Ptr("team1"), Permission: github.Ptr("pull"), Permissions: map[string]bool{"pull": true}},
+			}))
+			return
+		}
+		_, _ = w.Write(mock.MustMarshal([]github.Team{
+			{Slug: github.Ptr("team2"), Permission: github.Ptr("maintain"), Permissions: map[string]bool{"pull": true, "triage": true, "push": true, "maintain": true}},
+		}))
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c := github.NewClient(server.Client())
+	c.BaseURL, _ = url.Parse(server.URL + "/")
+	return server, c
+}
+
+func TestGraphQLLister(t *testing.T) {
+	requests := map[string]int{}
+	server, c := newGraphQLServer(t, requests)
+
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @org/team1 @org/team2 @user1\n/docs/ user2@example.com nobody@example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := PlanRuleset(context.Background(), c, "org", "repo", ruleset, &Options{
+		Lister: NewGraphQLLister(c, server.URL+"/graphql"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Action{
+		{Type: ActionGrantTeam, Owner: "@org/team1", Principal: "team1", Permission: "push", OldPermission: "pull", Rules: []RuleRef{{Pattern: "*", LineNumber: 1}}},
+		{Type: ActionGrantUser, Owner: "user2@example.com", Principal: "user2", Permission: "push", OldPermission: "read", Rules: []RuleRef{{Pattern: "/docs/", LineNumber: 2}}},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+	// Two pages of collaborators and one batch of emails. The teams take one
+	// REST request per page of repository teams, however many teams the
+	// organization has.
+	if diff := cmp.Diff(map[string]int{"/graphql": 3, "/repos/org/repo/teams": 2}, requests); diff != "" {
+		t.Errorf("unexpected number of requests\n%s", diff)
+	}
+}
+
+func TestPlanFileWithGraphQLLister(t *testing.T) {
+	requests := map[string]int{}
+	server, c := newGraphQLServer(t, requests)
+
+	file := &CodeownersFile{
+		Path:    "CODEOWNERS",
+		Content: []byte("* @org/team1 @user1\n"),
+	}
+	file.SHA = gitBlobSHA(file.Content)
+	ctx := context.Background()
+
+	opts := &Options{Lister: NewGraphQLLister(c, server.URL+"/graphql")}
+	plan, err := NewPlan(ctx, c, "org", "repo", file, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPlan(ctx, c, plan, file, opts); err != nil {
+		t.Errorf("expected the plan to be fresh, got %v", err)
+	}
+	// The state is read again through the same lister.
+	if diff := cmp.Diff(map[string]int{"/graphql": 4, "/repos/org/repo/teams": 4}, requests); diff != "" {
+		t.Errorf("unexpected number of requests\n%s", diff)
+	}
+}
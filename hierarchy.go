@@ -0,0 +1,91 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// inheritTeamPermissions adds the descendants of the repository teams to the
+// teams, with the permissions they inherit from their ancestors. A child team
+// inherits the repository permissions of its parent, but only the parent is
+// returned by Repositories.ListTeams. Walking down from the teams with access
+// finds the same inherited permissions as looking up the parents of every team
+// owner, with fewer requests.
+func inheritTeamPermissions(ctx context.Context, backend Backend, org string, teams []*github.Team) []*github.Team {
+	hierarchy, ok := backend.(TeamHierarchyBackend)
+	if !ok {
+		return teams
+	}
+
+	inherited := make([]*github.Team, 0, len(teams))
+	index := make(map[string]int)
+	for _, team := range teams {
+		index[stringify(team.Slug)] = len(inherited)
+		inherited = append(inherited, team)
+	}
+
+	childTeams := make(map[string][]*github.Team)
+	listChildTeams := func(slug string) []*github.Team {
+		if children, ok := childTeams[slug]; ok {
+			return children
+		}
+		children, err := hierarchy.ListChildTeams(ctx, org, slug)
+		if err != nil {
+			// Teams that are not visible to the token cannot be walked.
+			var errResp *github.ErrorResponse
+			if !errors.As(err, &errResp) || errResp.Response.StatusCode != http.StatusNotFound {
+				log.Println(err.Error())
+			}
+		}
+		childTeams[slug] = children
+		return children
+	}
+
+	var walk func(parent *github.Team, visited map[string]bool)
+	walk = func(parent *github.Team, visited map[string]bool) {
+		slug := stringify(parent.Slug)
+		if visited[slug] {
+			return
+		}
+		visited[slug] = true
+
+		for _, child := range listChildTeams(slug) {
+			childSlug := stringify(child.Slug)
+			i, ok := index[childSlug]
+			if !ok {
+				i = len(inherited)
+				index[childSlug] = i
+				inherited = append(inherited, &github.Team{
+					ID:   child.ID,
+					Name: child.Name,
+					Slug: child.Slug,
+				})
+			}
+			inherited[i] = mergeTeamPermissions(inherited[i], parent.Permissions)
+			walk(inherited[i], visited)
+		}
+	}
+
+	for _, team := range teams {
+		walk(team, make(map[string]bool))
+	}
+
+	return inherited
+}
+
+// mergeTeamPermissions returns a copy of the team that also has the permissions.
+func mergeTeamPermissions(team *github.Team, permissions map[string]bool) *github.Team {
+	merged := *team
+	merged.Permissions = make(map[string]bool, len(team.Permissions)+len(permissions))
+	for k, v := range team.Permissions {
+		merged.Permissions[k] = v
+	}
+	for k, v := range permissions {
+		merged.Permissions[k] = merged.Permissions[k] || v
+	}
+	return &merged
+}
@@ -0,0 +1,50 @@
+package codeownerizer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ReadIgnoreFile reads the owners listed in an ignore file, one per line as
+// written in CODEOWNERS. Blank lines and lines starting with # are skipped. A
+// missing file lists no owners.
+func ReadIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var owners []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		owners = append(owners, line)
+	}
+	return owners, scanner.Err()
+}
+
+// AppendIgnoreFile appends the owners to an ignore file, creating it if needed.
+func AppendIgnoreFile(path string, owners []string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if _, err := fmt.Fprintln(f, owner); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,157 @@
+// Package forge holds what the clients of the forges other than GitHub share:
+// sending JSON requests to their REST APIs, and annotating and applying the
+// actions planned for their CODEOWNERS files.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grezar/codeownerizer"
+)
+
+// ErrorResponse is an error returned by the API of a forge.
+type ErrorResponse struct {
+	// API names the API, such as GitLab.
+	API        string
+	StatusCode int
+	Message    string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s API returned %d: %s", e.API, e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether the error is a 404 response.
+func IsNotFound(err error) bool {
+	var errorResponse *ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.StatusCode == http.StatusNotFound
+}
+
+// Client sends requests to the REST API of a forge.
+type Client struct {
+	// API names the API in errors.
+	API string
+	// BaseURL is the URL the paths of requests are appended to.
+	BaseURL string
+	// AuthHeader is the header carrying AuthValue when it is set.
+	AuthHeader string
+	AuthValue  string
+	HTTPClient *http.Client
+}
+
+// Do sends a request to the path, which must be escaped, and decodes the
+// response into out unless it is nil. A *[]byte out receives the raw body.
+// Responses with a status of 300 or more are returned along with an
+// *ErrorResponse.
+func (c *Client) Do(ctx context.Context, method string, path string, query url.Values, body any, out any) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.AuthValue != "" {
+		req.Header.Set(c.AuthHeader, c.AuthValue)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// GitLab reports validation errors as an object in the message.
+		var e struct {
+			Message any `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &e) == nil && e.Message != nil && e.Message != "" {
+			message = fmt.Sprint(e.Message)
+		}
+		return resp, &ErrorResponse{API: c.API, StatusCode: resp.StatusCode, Message: message}
+	}
+	if out == nil {
+		return resp, nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		*raw, err = io.ReadAll(resp.Body)
+		return resp, err
+	}
+	return resp, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// IsIgnored reports whether the owner is among the ignored owners, compared
+// case-insensitively.
+func IsIgnored(ignored []string, owner string) bool {
+	for _, i := range ignored {
+		if strings.EqualFold(i, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule is a CODEOWNERS rule with its owners as written.
+type Rule struct {
+	Pattern    string
+	LineNumber int
+	Owners     []string
+}
+
+// AnnotateRules sets the rules that list the owner of each action.
+func AnnotateRules(actions []codeownerizer.Action, rules []Rule) []codeownerizer.Action {
+	for i := range actions {
+		for _, rule := range rules {
+			for _, owner := range rule.Owners {
+				if owner == actions[i].Owner {
+					actions[i].Rules = append(actions[i].Rules, codeownerizer.RuleRef{
+						Pattern:    rule.Pattern,
+						LineNumber: rule.LineNumber,
+					})
+					break
+				}
+			}
+		}
+	}
+	return actions
+}
+
+// Apply applies each action and logs what describe says about it. Failing to
+// apply an action is logged and does not stop the others from being applied.
+func Apply(actions []codeownerizer.Action, apply func(codeownerizer.Action) error, describe func(codeownerizer.Action) string) {
+	for _, action := range actions {
+		if err := apply(action); err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		log.Println(describe(action))
+	}
+}
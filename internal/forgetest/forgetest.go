@@ -0,0 +1,59 @@
+// Package forgetest serves fake forge APIs for the tests of the forge
+// packages.
+package forgetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Server is a fake forge API. It rejects requests without the expected
+// authentication header and serves one request at a time, so that handlers can
+// share state without locking.
+type Server struct {
+	*httptest.Server
+	mux        *http.ServeMux
+	mu         sync.Mutex
+	authHeader string
+	authValue  string
+}
+
+// NewServer starts a server expecting the header to be set to the value. It
+// is closed when the test ends.
+func NewServer(t *testing.T, authHeader string, authValue string) *Server {
+	t.Helper()
+	s := &Server{mux: http.NewServeMux(), authHeader: authHeader, authValue: authValue}
+	s.Server = httptest.NewServer(s.mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// HandleFunc registers the handler for the pattern.
+func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(s.authHeader) != s.authValue {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		handler(w, r)
+	})
+}
+
+// Handle registers a handler returning the response body and status for the
+// pattern. A string body is written as it is, and any other body as JSON.
+func (s *Server) Handle(pattern string, handler func(r *http.Request) (any, int)) {
+	s.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		body, status := handler(r)
+		w.WriteHeader(status)
+		if raw, ok := body.(string); ok {
+			_, _ = w.Write([]byte(raw))
+		} else if body != nil {
+			_ = json.NewEncoder(w).Encode(body)
+		}
+	})
+}
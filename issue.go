@@ -0,0 +1,70 @@
+package codeownerizer
+
+import (
+	"context"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// UpsertIssue opens an issue with the title, or updates the body of the open
+// issue with the same title, so that repeated runs keep a single issue.
+func UpsertIssue(ctx context.Context, api *github.Client, org string, repo string, title string, body string) (*github.Issue, error) {
+	issue, err := findOpenIssue(ctx, api, org, repo, title)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		issue, _, err = api.Issues.Create(ctx, org, repo, &github.IssueRequest{
+			Title: github.Ptr(title),
+			Body:  github.Ptr(body),
+		})
+		return issue, err
+	}
+	if issue.GetBody() == body {
+		return issue, nil
+	}
+	issue, _, err = api.Issues.Edit(ctx, org, repo, issue.GetNumber(), &github.IssueRequest{
+		Body: github.Ptr(body),
+	})
+	return issue, err
+}
+
+// CloseIssue closes the open issue with the title with a comment. It does
+// nothing when there is no such issue.
+func CloseIssue(ctx context.Context, api *github.Client, org string, repo string, title string, comment string) error {
+	issue, err := findOpenIssue(ctx, api, org, repo, title)
+	if err != nil || issue == nil {
+		return err
+	}
+	if _, _, err := api.Issues.CreateComment(ctx, org, repo, issue.GetNumber(), &github.IssueComment{
+		Body: github.Ptr(comment),
+	}); err != nil {
+		return err
+	}
+	_, _, err = api.Issues.Edit(ctx, org, repo, issue.GetNumber(), &github.IssueRequest{
+		State: github.Ptr("closed"),
+	})
+	return err
+}
+
+func findOpenIssue(ctx context.Context, api *github.Client, org string, repo string, title string) (*github.Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		issues, resp, err := api.Issues.ListByRepo(ctx, org, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && issue.GetTitle() == title {
+				return issue, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,82 @@
+package codeownerizer
+
+import (
+	"context"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// AccessLister reads who has access to a repository and who the email owners
+// are. The REST API pages through teams and collaborators and searches emails
+// one by one, while the GraphQL API batches them.
+type AccessLister interface {
+	// ListTeams lists the teams with direct access to the repository, with
+	// their Permissions set.
+	ListTeams(ctx context.Context, org string, repo string) ([]*github.Team, error)
+	// ListCollaborators lists the users with access to the repository, with
+	// their Permissions set.
+	ListCollaborators(ctx context.Context, org string, repo string) ([]*github.User, error)
+	// ResolveEmails finds the logins of the users who have each email.
+	ResolveEmails(ctx context.Context, emails []string) (map[string][]string, error)
+}
+
+// RESTLister is an AccessLister using the REST API.
+type RESTLister struct {
+	api *github.Client
+}
+
+func NewRESTLister(api *github.Client) *RESTLister {
+	return &RESTLister{api: api}
+}
+
+func (l *RESTLister) ListTeams(ctx context.Context, org string, repo string) ([]*github.Team, error) {
+	return ListTeams(ctx, l.api, org, repo)
+}
+
+func (l *RESTLister) ListCollaborators(ctx context.Context, org string, repo string) ([]*github.User, error) {
+	return ListCollaborators(ctx, l.api, org, repo)
+}
+
+func (l *RESTLister) ResolveEmails(ctx context.Context, emails []string) (map[string][]string, error) {
+	resolved := make(map[string][]string, len(emails))
+	for _, email := range emails {
+		logins, err := searchEmailLogins(ctx, l.api, email)
+		if err != nil {
+			return nil, err
+		}
+		resolved[email] = logins
+	}
+	return resolved, nil
+}
+
+// emailOwner is the result of resolving an email owner.
+type emailOwner struct {
+	login string
+	err   error
+}
+
+// resolveEmailOwners resolves all email owners at once through the lister.
+func resolveEmailOwners(ctx context.Context, lister AccessLister, owners []codeowners.Owner) map[string]emailOwner {
+	var emails []string
+	for _, owner := range owners {
+		if owner.Type == codeowners.EmailOwner {
+			emails = append(emails, owner.String())
+		}
+	}
+	resolved := make(map[string]emailOwner, len(emails))
+	if len(emails) == 0 {
+		return resolved
+	}
+
+	logins, err := lister.ResolveEmails(ctx, emails)
+	for _, email := range emails {
+		if err != nil {
+			resolved[email] = emailOwner{err: err}
+			continue
+		}
+		login, err := onlyLogin(email, logins[email])
+		resolved[email] = emailOwner{login: login, err: err}
+	}
+	return resolved
+}
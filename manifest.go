@@ -0,0 +1,175 @@
+package codeownerizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"gopkg.in/yaml.v3"
+)
+
+// RepoManifest lists the repositories to reconcile, with per-repository
+// overrides.
+type RepoManifest struct {
+	Repos []ManifestRepo `yaml:"repos"`
+}
+
+// ManifestRepo is a repository of a RepoManifest.
+type ManifestRepo struct {
+	// Repo is the repository as owner/name.
+	Repo string `yaml:"repo"`
+	// Ref is the Git ref to read CODEOWNERS from. Defaults to the default
+	// branch.
+	Ref string `yaml:"ref,omitempty"`
+	// Path is the path of the CODEOWNERS file. Defaults to the first standard
+	// location it exists at.
+	Path string `yaml:"path,omitempty"`
+	// Permission overrides the permission granted to code owners.
+	Permission string `yaml:"permission,omitempty"`
+}
+
+// split returns the owner and the name of the repository.
+func (r ManifestRepo) split() (string, string) {
+	org, name, _ := strings.Cut(r.Repo, "/")
+	return org, name
+}
+
+// ReadRepoManifest reads a YAML manifest listing the repositories:
+//
+//	repos:
+//	  - repo: org/api
+//	  - repo: web
+//	    ref: release
+//	    path: docs/CODEOWNERS
+//	    permission: maintain
+//
+// Repositories given without an owner belong to the default organization.
+func ReadRepoManifest(path string, defaultOrg string) (*RepoManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest RepoManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range manifest.Repos {
+		repo := &manifest.Repos[i]
+		if !strings.Contains(repo.Repo, "/") && repo.Repo != "" {
+			if defaultOrg == "" {
+				return nil, fmt.Errorf("%s: %s has no owner and no organization is given", path, repo.Repo)
+			}
+			repo.Repo = defaultOrg + "/" + repo.Repo
+		}
+		if org, name := repo.split(); org == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("%s: invalid repository: %q", path, repo.Repo)
+		}
+		if seen[strings.ToLower(repo.Repo)] {
+			return nil, fmt.Errorf("%s: %s is listed twice", path, repo.Repo)
+		}
+		seen[strings.ToLower(repo.Repo)] = true
+	}
+	if len(manifest.Repos) == 0 {
+		return nil, fmt.Errorf("%s lists no repository", path)
+	}
+	return &manifest, nil
+}
+
+// RepoResult is the outcome of reconciling one repository of a manifest.
+type RepoResult struct {
+	Repo string `json:"repo"`
+	// Actions are the grants that were planned. Failing grants are logged.
+	Actions []Action `json:"actions"`
+	// Convergence is the state of the repository after granting.
+	Convergence *Convergence `json:"convergence,omitempty"`
+	// Err stops the repository from being reconciled, for example a missing
+	// CODEOWNERS file.
+	Err error `json:"-"`
+}
+
+// Failed reports whether the repository failed or still has owners below the
+// target permission.
+func (r *RepoResult) Failed() bool {
+	return r.Err != nil || r.Convergence == nil || !r.Convergence.Converged()
+}
+
+// ReconcileManifest grants the code owners of every repository of the
+// manifest, like AddUngrantedOwners, and then verifies that each of them has
+// converged. A failing repository does not stop the others.
+func ReconcileManifest(ctx context.Context, api *github.Client, manifest *RepoManifest, opts *Options) []RepoResult {
+	return runManifest(ctx, api, manifest, opts, true)
+}
+
+// VerifyManifest verifies that every repository of the manifest has converged
+// without granting anything.
+func VerifyManifest(ctx context.Context, api *github.Client, manifest *RepoManifest, opts *Options) []RepoResult {
+	return runManifest(ctx, api, manifest, opts, false)
+}
+
+func runManifest(ctx context.Context, api *github.Client, manifest *RepoManifest, opts *Options, apply bool) []RepoResult {
+	if opts == nil {
+		opts = &Options{}
+	}
+	results := make([]RepoResult, 0, len(manifest.Repos))
+	for _, repo := range manifest.Repos {
+		result := RepoResult{Repo: repo.Repo}
+		result.Actions, result.Convergence, result.Err = reconcileManifestRepo(ctx, api, repo, opts, apply)
+		results = append(results, result)
+	}
+	return results
+}
+
+func reconcileManifestRepo(ctx context.Context, api *github.Client, repo ManifestRepo, opts *Options, apply bool) ([]Action, *Convergence, error) {
+	org, name := repo.split()
+	var file *CodeownersFile
+	var err error
+	if repo.Path != "" {
+		file, err = GetCodeownersFile(ctx, api, org, name, repo.Ref, repo.Path)
+		if err == nil && file == nil {
+			err = fmt.Errorf("%s does not exist in %s", repo.Path, repo.Repo)
+		}
+	} else {
+		file, err = FetchCodeownersFile(ctx, api, org, name, repo.Ref)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ruleset, err := file.Ruleset()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", file.Path, err)
+	}
+
+	repoOpts := *opts
+	if repo.Permission != "" {
+		repoOpts.Permission = repo.Permission
+	}
+
+	var actions []Action
+	if apply {
+		actions, err = PlanRuleset(ctx, api, org, name, ruleset, &repoOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+		ApplyActions(ctx, api, org, name, actions, &repoOpts)
+	}
+	// The access is read again only when it may have changed.
+	var convergence *Convergence
+	if apply && len(actions) == 0 {
+		convergence, err = PlanConvergence(ctx, api, org, name, actions, &repoOpts)
+	} else {
+		convergence, err = VerifyConvergence(ctx, api, org, name, ruleset, &repoOpts)
+	}
+	if err != nil {
+		return actions, nil, err
+	}
+	return actions, convergence, nil
+}
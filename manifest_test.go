@@ -0,0 +1,53 @@
+package codeownerizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadRepoManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "repos.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	manifest, err := ReadRepoManifest(write(`
+repos:
+  - repo: other/api
+  - repo: web
+    ref: release
+    path: docs/CODEOWNERS
+    permission: maintain
+`), "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &RepoManifest{Repos: []ManifestRepo{
+		{Repo: "other/api"},
+		{Repo: "org/web", Ref: "release", Path: "docs/CODEOWNERS", Permission: "maintain"},
+	}}
+	if diff := cmp.Diff(want, manifest); diff != "" {
+		t.Errorf("unexpected manifest\n%s", diff)
+	}
+
+	for content, want := range map[string]string{
+		"":                                       "lists no repository",
+		"repos:\n  - repo: web\n":                "web has no owner and no organization is given",
+		"repos:\n  - repo: a/b/c\n":              `invalid repository: "a/b/c"`,
+		"repos:\n  - repo: a/b\n  - repo: A/B\n": "A/B is listed twice",
+		"repos:\n  - repo: a/b\n    branch: x\n": "field branch not found",
+	} {
+		_, err := ReadRepoManifest(write(content), "")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ReadRepoManifest(%q) returned %v, want %s", content, err, want)
+		}
+	}
+}
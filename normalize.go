@@ -0,0 +1,124 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer/codeownersfile"
+)
+
+// EmailNormalization describes how an email owner was normalized.
+type EmailNormalization struct {
+	Email string `json:"email"`
+	// Username is the login the email was replaced with.
+	Username string `json:"username,omitempty"`
+	// Reason explains why the email was left as it is.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NormalizeEmailOwners resolves every email owner in the CODEOWNERS content
+// once and replaces it with the @username of the user, or removes it when the
+// rule already lists the user. Emails that resolve to no user or to several
+// users are left as they are, with a comment on the rules listing them. It
+// fails on any other error from the API.
+func NormalizeEmailOwners(ctx context.Context, api *github.Client, content []byte) ([]byte, []EmailNormalization, error) {
+	file := codeownersfile.ParseBytes(content)
+
+	resolved := make(map[string]*EmailNormalization)
+	var normalizations []EmailNormalization
+	for _, line := range file.Rules() {
+		for _, owner := range line.Owners {
+			if !isEmailOwner(owner.Value) {
+				continue
+			}
+			key := strings.ToLower(owner.Value)
+			normalization, ok := resolved[key]
+			if !ok {
+				username, err := resolveEmailOwner(ctx, api, owner.Value)
+				var resolutionErr *EmailResolutionError
+				switch {
+				case errors.As(err, &resolutionErr):
+					normalization = &EmailNormalization{Email: owner.Value, Reason: emailResolutionReason(resolutionErr)}
+				case err != nil:
+					return nil, nil, err
+				default:
+					normalization = &EmailNormalization{Email: owner.Value, Username: username}
+				}
+				resolved[key] = normalization
+				normalizations = append(normalizations, *normalization)
+			}
+
+			if normalization.Username != "" {
+				continue
+			}
+			note := fmt.Sprintf("codeownerizer: %s %s", owner.Value, normalization.Reason)
+			switch {
+			case strings.Contains(line.Comment, note):
+			case line.Comment == "":
+				line.SetComment("# " + note)
+			default:
+				line.SetComment(line.Comment + "; " + note)
+			}
+		}
+	}
+
+	// Emails are replaced once resolved, dropping those whose user the rule
+	// already lists.
+	file.MapOwners(func(owner string) string {
+		if normalization, ok := resolved[strings.ToLower(owner)]; ok && normalization.Username != "" {
+			return "@" + normalization.Username
+		}
+		return owner
+	})
+	return file.Bytes(), normalizations, nil
+}
+
+func isEmailOwner(owner string) bool {
+	return !strings.HasPrefix(owner, "@") && strings.Contains(owner, "@")
+}
+
+func emailResolutionReason(err *EmailResolutionError) string {
+	if err.Matches == 0 {
+		return "matches no user"
+	}
+	return "matches multiple users"
+}
+
+// NormalizeEmailOwnersPullRequest opens or updates a pull request normalizing
+// the email owners in the CODEOWNERS file on the default branch. It returns a
+// nil pull request when there is nothing to change.
+func NormalizeEmailOwnersPullRequest(ctx context.Context, api *github.Client, org string, repo string) (*github.PullRequest, []EmailNormalization, error) {
+	file, err := FetchCodeownersFile(ctx, api, org, repo, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	content, normalizations, err := NormalizeEmailOwners(ctx, api, file.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if string(content) == string(file.Content) {
+		return nil, normalizations, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("This pull request replaces the email owners in CODEOWNERS with the usernames they resolve to, ")
+	b.WriteString("which saves a user search on every run and keeps the owners from changing over time.\n\n")
+	for _, n := range normalizations {
+		if n.Username != "" {
+			fmt.Fprintf(&b, "- `%s` → `@%s`\n", n.Email, n.Username)
+		} else {
+			fmt.Fprintf(&b, "- `%s` is left as it is: it %s\n", n.Email, n.Reason)
+		}
+	}
+
+	pull, err := UpsertPullRequest(ctx, api, org, repo, file, content, &PullRequestOptions{
+		Branch:        "codeownerizer/normalize-email-owners",
+		Title:         "Replace email owners in CODEOWNERS with usernames",
+		Body:          b.String(),
+		CommitMessage: "Replace email owners in CODEOWNERS with usernames",
+	})
+	return pull, normalizations, err
+}
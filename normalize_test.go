@@ -0,0 +1,74 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestNormalizeEmailOwners(t *testing.T) {
+	content := "# Docs\n" +
+		"*       octocat@example.com   @org/team1\n" +
+		"/docs/  OCTOCAT@example.com gone@example.com # docs\n" +
+		"/src/   shared@example.com\n" +
+		"/api/   @octocat octocat@example.com\n"
+
+	searches := 0
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetSearchUsers,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				searches++
+				var users []*github.User
+				switch q := r.URL.Query().Get("q"); {
+				case strings.HasPrefix(strings.ToLower(q), "octocat@"):
+					users = []*github.User{{Login: github.Ptr("octocat")}}
+				case strings.HasPrefix(q, "shared@"):
+					users = []*github.User{{Login: github.Ptr("a")}, {Login: github.Ptr("b")}}
+				}
+				_, _ = w.Write(mock.MustMarshal(github.UsersSearchResult{Total: github.Ptr(len(users)), Users: users}))
+			}),
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+
+	got, normalizations, err := NormalizeEmailOwners(context.Background(), c, []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# Docs\n" +
+		"*       @octocat   @org/team1\n" +
+		"/docs/  @octocat gone@example.com # docs; codeownerizer: gone@example.com matches no user\n" +
+		"/src/   shared@example.com # codeownerizer: shared@example.com matches multiple users\n" +
+		"/api/   @octocat\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("unexpected content\n%s", diff)
+	}
+
+	wantNormalizations := []EmailNormalization{
+		{Email: "octocat@example.com", Username: "octocat"},
+		{Email: "gone@example.com", Reason: "matches no user"},
+		{Email: "shared@example.com", Reason: "matches multiple users"},
+	}
+	if diff := cmp.Diff(wantNormalizations, normalizations); diff != "" {
+		t.Errorf("unexpected normalizations\n%s", diff)
+	}
+	if diff := cmp.Diff(3, searches); diff != "" {
+		t.Errorf("expected each email to be resolved once\n%s", diff)
+	}
+
+	// Normalizing again does not repeat the comments.
+	again, _, err := NormalizeEmailOwners(context.Background(), c, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, string(again)); diff != "" {
+		t.Errorf("unexpected content after normalizing again\n%s", diff)
+	}
+}
@@ -0,0 +1,211 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// pushRoles are the base repository roles that include the push permission.
+var pushRoles = map[string]bool{
+	"write":    true,
+	"maintain": true,
+	"admin":    true,
+}
+
+// Permission is the permission of a team or a user on a repository as
+// reported by the permission-level endpoints.
+type Permission struct {
+	// Push is true when the principal can push to the repository.
+	Push bool `json:"push"`
+	// RoleName is the repository role, which is either a base role such as
+	// "write" or the name of a custom repository role.
+	RoleName string `json:"role_name,omitempty"`
+}
+
+// GetUserPermission asks GitHub for the permission of the user on the
+// repository. Unlike the Permissions map of ListCollaborators, it reflects
+// custom repository roles and access through enterprise ownership.
+func GetUserPermission(ctx context.Context, api *github.Client, org string, repo string, username string) (*Permission, error) {
+	level, resp, err := api.Repositories.GetPermissionLevel(ctx, org, repo, username)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return &Permission{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = github.CheckResponse(resp.Response); err != nil {
+		return nil, err
+	}
+	// The legacy permission field maps custom roles to their base role.
+	legacy := stringify(level.Permission)
+	return &Permission{
+		Push:     pushRoles[stringify(level.RoleName)] || legacy == "write" || legacy == "admin",
+		RoleName: stringify(level.RoleName),
+	}, nil
+}
+
+// GetTeamPermission asks GitHub for the permission of the team on the
+// repository, including the permission inherited from its parent teams.
+func GetTeamPermission(ctx context.Context, api *github.Client, org string, repo string, slug string) (*Permission, error) {
+	repository, resp, err := api.Teams.IsTeamRepoBySlug(ctx, org, slug, org, repo)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return &Permission{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = github.CheckResponse(resp.Response); err != nil {
+		return nil, err
+	}
+	return &Permission{
+		Push:     pushRoles[stringify(repository.RoleName)] || repository.Permissions[pushPermission],
+		RoleName: stringify(repository.RoleName),
+	}, nil
+}
+
+// teamHasPushPermission reports whether the team can push, using either the
+// repository teams or, in the accurate mode, the permission-level endpoint.
+func teamHasPushPermission(ctx context.Context, backend Backend, org string, repo string, teams []*github.Team, slug string, opts *Options) (bool, error) {
+	if !opts.Accurate {
+		return hasTeamOwnerSufficientPermission(teams, slug) && containsTeamOwner(teams, slug), nil
+	}
+	levels, err := permissionLevels(backend)
+	if err != nil {
+		return false, err
+	}
+	permission, err := levels.GetTeamPermission(ctx, org, repo, slug)
+	if err != nil {
+		return false, err
+	}
+	return permission.Push, nil
+}
+
+// userHasPushPermission reports whether the user can push, using either the
+// repository collaborators or, in the accurate mode, the permission-level
+// endpoint.
+func userHasPushPermission(ctx context.Context, backend Backend, org string, repo string, collaborators []*github.User, username string, opts *Options) (bool, error) {
+	if !opts.Accurate {
+		return hasUserOwnerSufficientPermission(collaborators, username) && containsUserOwner(collaborators, username), nil
+	}
+	levels, err := permissionLevels(backend)
+	if err != nil {
+		return false, err
+	}
+	permission, err := levels.GetUserPermission(ctx, org, repo, username)
+	if err != nil {
+		return false, err
+	}
+	return permission.Push, nil
+}
+
+func permissionLevels(backend Backend) (PermissionLevelBackend, error) {
+	levels, ok := backend.(PermissionLevelBackend)
+	if !ok {
+		return nil, errors.New("the accurate mode is not supported by the backend")
+	}
+	return levels, nil
+}
+
+// PermissionComparison compares the push permission of an owner as read from
+// the repository teams and collaborators with the one reported by the
+// permission-level endpoints.
+type PermissionComparison struct {
+	Owner string `json:"owner"`
+	// Fast is the result of reading the Permissions maps.
+	Fast bool `json:"fast"`
+	// Accurate is the result of the permission-level endpoints.
+	Accurate bool   `json:"accurate"`
+	RoleName string `json:"role_name,omitempty"`
+	// Error explains why the owner could not be compared.
+	Error string `json:"error,omitempty"`
+}
+
+// Mismatch reports whether the two modes disagree. Owners that could not be
+// compared do not.
+func (c PermissionComparison) Mismatch() bool {
+	return c.Error == "" && c.Fast != c.Accurate
+}
+
+// ComparePermissions checks the push permission of every owner in both the
+// fast and the accurate mode, through the Backend and Lister of the options.
+// Aliases are replaced by the owners they stand for and ignored owners are
+// skipped. Owners that cannot be checked are reported with an error instead
+// of stopping the comparison.
+func ComparePermissions(ctx context.Context, api *github.Client, org string, repo string, owners []codeowners.Owner, opts *Options) ([]PermissionComparison, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	if err := opts.Aliases.Validate(); err != nil {
+		return nil, err
+	}
+	var checked []codeowners.Owner
+	for _, owner := range uniqueOwners(opts.Aliases.expandOwners(owners, opts.isIgnored)) {
+		if !opts.isIgnored(owner.String()) {
+			checked = append(checked, owner)
+		}
+	}
+	owners = checked
+
+	backend := opts.backend(api)
+	levels, err := permissionLevels(backend)
+	if err != nil {
+		return nil, err
+	}
+	lister := opts.lister(api)
+
+	teams, err := lister.ListTeams(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	teams = inheritTeamPermissions(ctx, backend, org, teams)
+
+	collaborators, err := lister.ListCollaborators(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	teams, collaborators = applyCustomRoles(teams, collaborators, listPushCustomRoles(ctx, backend, org))
+
+	emails := resolveEmailOwners(ctx, lister, owners)
+
+	var comparisons []PermissionComparison
+	for _, owner := range owners {
+		comparison := PermissionComparison{Owner: owner.String()}
+		var permission *Permission
+		switch owner.Type {
+		case codeowners.TeamOwner:
+			teamOwnerName := strings.Split(owner.String(), "/")[1]
+			comparison.Fast = hasTeamOwnerSufficientPermission(teams, teamOwnerName)
+			permission, err = levels.GetTeamPermission(ctx, org, repo, teamOwnerName)
+		case codeowners.UsernameOwner:
+			userOwnerName := strings.TrimPrefix(owner.String(), "@")
+			comparison.Fast = hasUserOwnerSufficientPermission(collaborators, userOwnerName)
+			permission, err = levels.GetUserPermission(ctx, org, repo, userOwnerName)
+		case codeowners.EmailOwner:
+			email := emails[owner.String()]
+			err = email.err
+			if err == nil {
+				comparison.Fast = hasUserOwnerSufficientPermission(collaborators, email.login)
+				permission, err = levels.GetUserPermission(ctx, org, repo, email.login)
+			}
+		default:
+			err = errors.New("unknown owner type: " + owner.Type)
+		}
+		if err != nil {
+			comparison.Fast = false
+			comparison.Error = err.Error()
+			comparisons = append(comparisons, comparison)
+			continue
+		}
+		comparison.Accurate = permission.Push
+		comparison.RoleName = permission.RoleName
+		comparisons = append(comparisons, comparison)
+	}
+
+	return comparisons, nil
+}
@@ -0,0 +1,115 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestComparePermissions(t *testing.T) {
+	owners := []codeowners.Owner{
+		{Value: "cats", Type: codeowners.UsernameOwner},
+		{Value: "doctocat", Type: codeowners.UsernameOwner},
+		{Value: "ghost", Type: codeowners.UsernameOwner},
+		{Value: "nobody@example.com", Type: codeowners.EmailOwner},
+		{Value: "bot", Type: codeowners.UsernameOwner},
+	}
+	opts := &Options{
+		Aliases:       Aliases{"@cats": {"@octo-org/octocats", "@octocat"}},
+		IgnoredOwners: []string{"@bot"},
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				{
+					Slug: github.Ptr("octocats"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				{
+					Login: github.Ptr("octocat"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+				{
+					Login: github.Ptr("doctocat"),
+					Permissions: map[string]bool{
+						"push": false,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetOrgsTeamsReposByOrgByTeamSlugByOwnerByRepo,
+			github.Repository{
+				RoleName: github.Ptr("maintain"),
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposCollaboratorsPermissionByOwnerByRepoByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "/ghost/") {
+					mock.WriteError(w, http.StatusInternalServerError, "server error")
+					return
+				}
+				level := github.RepositoryPermissionLevel{
+					Permission: github.Ptr("admin"),
+					RoleName:   github.Ptr("admin"),
+				}
+				if strings.Contains(r.URL.Path, "/doctocat/") {
+					level = github.RepositoryPermissionLevel{
+						Permission: github.Ptr("write"),
+						RoleName:   github.Ptr("code-reviewer"),
+					}
+				}
+				_, _ = w.Write(mock.MustMarshal(level))
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.GetSearchUsers,
+			github.UsersSearchResult{Total: github.Ptr(0)},
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	comparisons, err := ComparePermissions(context.Background(), client, "org", "repo", owners, opts)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// A failing owner is reported without stopping the comparison.
+	for i := range comparisons {
+		if comparisons[i].Error != "" {
+			comparisons[i].Error = "error"
+		}
+	}
+	want := []PermissionComparison{
+		{Owner: "@octo-org/octocats", Fast: true, Accurate: true, RoleName: "maintain"},
+		{Owner: "@octocat", Fast: true, Accurate: true, RoleName: "admin"},
+		{Owner: "@doctocat", Fast: false, Accurate: true, RoleName: "code-reviewer"},
+		{Owner: "@ghost", Error: "error"},
+		{Owner: "nobody@example.com", Error: "error"},
+	}
+	if diff := cmp.Diff(want, comparisons); diff != "" {
+		t.Errorf("unexpected comparisons\n%s", diff)
+	}
+	if comparisons[1].Mismatch() || !comparisons[2].Mismatch() || comparisons[3].Mismatch() {
+		t.Errorf("unexpected mismatches\n%v", comparisons)
+	}
+}
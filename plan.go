@@ -0,0 +1,346 @@
+package codeownerizer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+const (
+	// ActionGrantTeam grants the permission on the repository to a team.
+	ActionGrantTeam = "grant_team"
+	// ActionGrantUser adds a user to the repository as a collaborator.
+	ActionGrantUser = "grant_user"
+	// ActionAddTeamMember adds a user to the designated codeowners team.
+	ActionAddTeamMember = "add_team_member"
+)
+
+// Action is a change that lets a code owner approve reviews on the repository.
+type Action struct {
+	Type string `json:"type"`
+	// Owner is the owner as written in CODEOWNERS.
+	Owner string `json:"owner"`
+	// Principal is the slug of the team or the login of the user to grant.
+	Principal  string `json:"principal"`
+	Permission string `json:"permission,omitempty"`
+	// Team is the team the user is added to by ActionAddTeamMember.
+	Team string `json:"team,omitempty"`
+	// OldPermission is the direct permission of the principal on the
+	// repository when the action was planned.
+	OldPermission string `json:"old_permission,omitempty"`
+	// Rules are the CODEOWNERS rules that list the owner.
+	Rules []RuleRef `json:"rules,omitempty"`
+	// Reason explains an action that no CODEOWNERS rule asked for, such as
+	// granting the designated codeowners team.
+	Reason string `json:"reason,omitempty"`
+}
+
+// RuleRef identifies a CODEOWNERS rule.
+type RuleRef struct {
+	Pattern    string `json:"pattern"`
+	LineNumber int    `json:"line_number"`
+}
+
+func (r RuleRef) String() string {
+	return fmt.Sprintf("line %d (%s)", r.LineNumber, r.Pattern)
+}
+
+func (a Action) String() string {
+	switch a.Type {
+	case ActionGrantTeam:
+		return fmt.Sprintf("grant %s to team %s", a.Permission, a.Principal)
+	case ActionGrantUser:
+		return fmt.Sprintf("grant %s to user %s", a.Permission, a.Principal)
+	case ActionAddTeamMember:
+		return fmt.Sprintf("add user %s to team %s", a.Principal, a.Team)
+	default:
+		return fmt.Sprintf("%s %s", a.Type, a.Principal)
+	}
+}
+
+// PlanGrants computes the actions that grant the permission to the code owners
+// who lack it, without changing anything. Owners that cannot be checked are
+// logged and left out of the plan.
+func PlanGrants(ctx context.Context, api *github.Client, org string, repo string, owners []codeowners.Owner, opts *Options) ([]Action, error) {
+	actions, _, err := planGrants(ctx, api, org, repo, owners, opts)
+	return actions, err
+}
+
+// planGrants computes the actions together with the state of the repository
+// they were computed from.
+func planGrants(ctx context.Context, api *github.Client, org string, repo string, owners []codeowners.Owner, opts *Options) ([]Action, *State, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	if err := opts.Aliases.Validate(); err != nil {
+		return nil, nil, err
+	}
+	owners = uniqueOwners(opts.Aliases.expandOwners(owners, opts.isIgnored))
+	backend := opts.backend(api)
+	lister := opts.lister(api)
+
+	teams, err := lister.ListTeams(ctx, org, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	collaborators, err := lister.ListCollaborators(ctx, org, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state := newState(teams, collaborators)
+	teams = inheritTeamPermissions(ctx, backend, org, teams)
+
+	customRoles := listPushCustomRoles(ctx, backend, org)
+	if err := validatePermission(opts.permission(), customRoles); err != nil {
+		return nil, nil, err
+	}
+	teams, collaborators = applyCustomRoles(teams, collaborators, customRoles)
+
+	emails := resolveEmailOwners(ctx, lister, owners)
+
+	var actions []Action
+	planned := make(map[string]bool)
+	add := func(action *Action) {
+		if action == nil {
+			return
+		}
+		key := action.Type + " " + action.Principal
+		if planned[key] {
+			return
+		}
+		planned[key] = true
+		switch action.Type {
+		case ActionGrantTeam:
+			action.OldPermission = state.Teams[action.Principal]
+		case ActionGrantUser:
+			action.OldPermission = state.Collaborators[action.Principal]
+		}
+		actions = append(actions, *action)
+	}
+
+	for _, owner := range owners {
+		if opts.isIgnored(owner.String()) {
+			continue
+		}
+
+		var action *Action
+		switch owner.Type {
+		case codeowners.TeamOwner:
+			teamOwnerName := strings.Split(owner.String(), "/")[1]
+			action, err = planTeamOwner(ctx, backend, org, repo, teams, owner.String(), teamOwnerName, opts)
+		case codeowners.UsernameOwner:
+			userOwnerName := strings.TrimPrefix(owner.String(), "@")
+			action, err = planUserOwner(ctx, backend, org, repo, teams, collaborators, owner.String(), userOwnerName, opts)
+		case codeowners.EmailOwner:
+			email := emails[owner.String()]
+			if err = email.err; err != nil {
+				break
+			}
+			action, err = planUserOwner(ctx, backend, org, repo, teams, collaborators, owner.String(), email.login, opts)
+		default:
+			err = fmt.Errorf("unknown owner type: %s", owner.Type)
+		}
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		add(action)
+	}
+
+	// The designated codeowners team must be able to push itself, otherwise
+	// adding users to it does not make them eligible to approve. It is left
+	// alone when no user is added to it.
+	if opts.CodeownersTeam != "" && addsTeamMembers(actions) {
+		action, err := planTeamOwner(ctx, backend, org, repo, teams, "@"+org+"/"+opts.CodeownersTeam, opts.CodeownersTeam, opts)
+		if err != nil {
+			log.Println(err.Error())
+		} else if action != nil {
+			action.Reason = "code owners are added to the codeowners team " + opts.CodeownersTeam
+			add(action)
+		}
+	}
+
+	return actions, state, nil
+}
+
+func addsTeamMembers(actions []Action) bool {
+	for _, action := range actions {
+		if action.Type == ActionAddTeamMember {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanRuleset computes the actions for the owners of the rules, like
+// PlanGrants, and records which rules list each owner.
+func PlanRuleset(ctx context.Context, api *github.Client, org string, repo string, ruleset codeowners.Ruleset, opts *Options) ([]Action, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	ruleset, err := opts.expandRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+	actions, _, err := planGrants(ctx, api, org, repo, rulesetOwners(ruleset), opts)
+	if err != nil {
+		return nil, err
+	}
+	return annotateRules(actions, ruleset), nil
+}
+
+// rulesetOwners lists the owners of all rules.
+func rulesetOwners(ruleset codeowners.Ruleset) []codeowners.Owner {
+	var owners []codeowners.Owner
+	for _, rule := range ruleset {
+		owners = append(owners, rule.Owners...)
+	}
+	return owners
+}
+
+// annotateRules sets the rules that list the owner of each action. Actions
+// with a reason were not asked for by any rule.
+func annotateRules(actions []Action, ruleset codeowners.Ruleset) []Action {
+	for i := range actions {
+		if actions[i].Reason != "" {
+			continue
+		}
+		for _, rule := range ruleset {
+			for _, owner := range rule.Owners {
+				if owner.String() == actions[i].Owner {
+					actions[i].Rules = append(actions[i].Rules, RuleRef{
+						Pattern:    rule.RawPattern(),
+						LineNumber: rule.LineNumber,
+					})
+					break
+				}
+			}
+		}
+	}
+	return actions
+}
+
+// ApplyActions applies the actions and records each successful one in the
+// audit log. Failing to apply an action is logged and does not stop the others
+// from being applied.
+func ApplyActions(ctx context.Context, api *github.Client, org string, repo string, actions []Action, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	backend := opts.backend(api)
+	for _, action := range actions {
+		if err := applyAction(ctx, backend, org, repo, action); err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		logAppliedAction(action)
+		if opts.Auditor != nil {
+			opts.Auditor.Record(ctx, org, repo, action)
+		}
+	}
+}
+
+// ApplyAction makes the change described by the action through the REST API.
+func ApplyAction(ctx context.Context, api *github.Client, org string, repo string, action Action) error {
+	return applyAction(ctx, NewRESTBackend(api), org, repo, action)
+}
+
+func applyAction(ctx context.Context, backend Backend, org string, repo string, action Action) error {
+	switch action.Type {
+	case ActionGrantTeam:
+		return backend.GrantTeam(ctx, org, repo, action.Principal, action.Permission)
+	case ActionGrantUser:
+		return backend.GrantUser(ctx, org, repo, action.Principal, action.Permission)
+	case ActionAddTeamMember:
+		return backend.AddTeamMember(ctx, org, action.Team, action.Principal)
+	default:
+		return fmt.Errorf("unknown action type: %s", action.Type)
+	}
+}
+
+// planTeamOwner plans to grant the permission to
+// - a team that is already have an access to the repository but does not have a push permission.
+// - a team that does not have an access to the repository.
+func planTeamOwner(ctx context.Context, backend Backend, org string, repo string, teams []*github.Team, owner string, slug string, opts *Options) (*Action, error) {
+	ok, err := teamHasPushPermission(ctx, backend, org, repo, teams, slug, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, nil
+	}
+	return &Action{
+		Type:       ActionGrantTeam,
+		Owner:      owner,
+		Principal:  slug,
+		Permission: opts.permission(),
+	}, nil
+}
+
+// planUserOwner plans to grant the permission to a user who does not have it
+// yet, either directly as a collaborator or through the designated codeowners
+// team.
+func planUserOwner(ctx context.Context, backend Backend, org string, repo string, teams []*github.Team, collaborators []*github.User, owner string, username string, opts *Options) (*Action, error) {
+	ok, err := userHasPushPermission(ctx, backend, org, repo, collaborators, username, opts)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, nil
+	}
+
+	if opts.PreferTeamAccess {
+		ok, err := belongsToTeamWithPushPermission(ctx, backend, org, teams, username)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return nil, nil
+		}
+	}
+
+	if opts.CodeownersTeam != "" {
+		return &Action{
+			Type:      ActionAddTeamMember,
+			Owner:     owner,
+			Principal: username,
+			Team:      opts.CodeownersTeam,
+		}, nil
+	}
+
+	return &Action{
+		Type:       ActionGrantUser,
+		Owner:      owner,
+		Principal:  username,
+		Permission: opts.permission(),
+	}, nil
+}
+
+// belongsToTeamWithPushPermission reports whether the user is an active member
+// of any of the given repository teams that has the push permission.
+func belongsToTeamWithPushPermission(ctx context.Context, backend Backend, org string, teams []*github.Team, username string) (bool, error) {
+	membership, ok := backend.(TeamMembershipBackend)
+	if !ok {
+		return false, nil
+	}
+	for _, team := range teams {
+		if !team.Permissions[pushPermission] {
+			continue
+		}
+		active, err := membership.IsActiveTeamMember(ctx, org, stringify(team.Slug), username)
+		if err != nil {
+			return false, err
+		}
+		if active {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,139 @@
+package codeownerizer
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestPlanGrants(t *testing.T) {
+	ruleset, err := codeowners.LoadFile("testdata/CODEOWNERS-USER")
+	if err != nil {
+		t.Error(err)
+	}
+	var owners []codeowners.Owner
+	for _, rule := range ruleset {
+		owners = append(owners, rule.Owners...)
+	}
+	owners = append(owners, codeowners.Owner{Value: "octo-org/octocats", Type: codeowners.TeamOwner})
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				{
+					Login: github.Ptr("octocat"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	actions, err := PlanGrants(context.Background(), client, "org", "repo", owners, &Options{
+		// Ignored owners are matched case-insensitively.
+		IgnoredOwners: []string{"@OctoCat2"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	want := []Action{
+		{Type: ActionGrantUser, Owner: "@doctocat", Principal: "doctocat", Permission: "push"},
+		{Type: ActionGrantTeam, Owner: "@octo-org/octocats", Principal: "octocats", Permission: "push"},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".codeownerizer-ignore")
+
+	owners, err := ReadIgnoreFile(path)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(owners) != 0 {
+		t.Errorf("expected no owners from a missing file, got %v", owners)
+	}
+
+	if err := AppendIgnoreFile(path, []string{"@octocat"}); err != nil {
+		t.Error(err)
+	}
+	if err := AppendIgnoreFile(path, []string{"@octo-org/octocats", "docs@example.com"}); err != nil {
+		t.Error(err)
+	}
+
+	owners, err = ReadIgnoreFile(path)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := cmp.Diff([]string{"@octocat", "@octo-org/octocats", "docs@example.com"}, owners); diff != "" {
+		t.Errorf("unexpected owners\n%s", diff)
+	}
+}
+
+func TestPlanCodeownersTeam(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader("* @user1\n/docs/ @org/codeowners @user2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &memoryBackend{
+		teams:         map[string]string{"codeowners": "read"},
+		collaborators: map[string]string{"user1": "write", "user2": "write"},
+	}
+	opts := &Options{Backend: backend, CodeownersTeam: "codeowners", Permission: "maintain"}
+	ctx := context.Background()
+
+	// The codeowners team is only granted along with the users added to it.
+	ownersWithPush := codeowners.Ruleset{ruleset[0]}
+	actions, err := PlanRuleset(ctx, nil, "org", "repo", ownersWithPush, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 0 {
+		t.Errorf("expected no actions, got %v", actions)
+	}
+
+	backend.collaborators = map[string]string{"user2": "read"}
+	actions, err = PlanRuleset(ctx, nil, "org", "repo", ownersWithPush, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Action{
+		{Type: ActionAddTeamMember, Owner: "@user1", Principal: "user1", Team: "codeowners", Rules: []RuleRef{{Pattern: "*", LineNumber: 1}}},
+		{Type: ActionGrantTeam, Owner: "@org/codeowners", Principal: "codeowners", Permission: "maintain", OldPermission: "read", Reason: "code owners are added to the codeowners team codeowners"},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+
+	// Listed in CODEOWNERS, the team is granted for its rules.
+	actions, err = PlanRuleset(ctx, nil, "org", "repo", ruleset, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []RuleRef{{Pattern: "/docs/", LineNumber: 2}}
+	want = []Action{
+		{Type: ActionAddTeamMember, Owner: "@user1", Principal: "user1", Team: "codeowners", Rules: []RuleRef{{Pattern: "*", LineNumber: 1}}},
+		{Type: ActionGrantTeam, Owner: "@org/codeowners", Principal: "codeowners", Permission: "maintain", OldPermission: "read", Rules: docs},
+		{Type: ActionAddTeamMember, Owner: "@user2", Principal: "user2", Team: "codeowners", Rules: docs},
+	}
+	if diff := cmp.Diff(want, actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+}
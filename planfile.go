@@ -0,0 +1,231 @@
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// planFormatVersion is the version of the plan file format.
+const planFormatVersion = 1
+
+// Plan is a set of actions saved for review, together with the CODEOWNERS file
+// and the repository state they were computed from. Applying a plan refuses to
+// proceed once either of them has changed.
+type Plan struct {
+	FormatVersion  int       `json:"format_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	Org            string    `json:"org"`
+	Repo           string    `json:"repo"`
+	CodeownersPath string    `json:"codeowners_path"`
+	CodeownersSHA  string    `json:"codeowners_sha"`
+	State          *State    `json:"state"`
+	Actions        []Action  `json:"actions"`
+}
+
+// State is the direct access of the teams and collaborators to a repository,
+// keyed by team slug and user login.
+type State struct {
+	Teams         map[string]string `json:"teams"`
+	Collaborators map[string]string `json:"collaborators"`
+}
+
+// permissionOrder lists the base permissions from the highest.
+var permissionOrder = []string{"admin", "maintain", "push", "triage", "pull"}
+
+func newState(teams []*github.Team, collaborators []*github.User) *State {
+	state := &State{
+		Teams:         make(map[string]string),
+		Collaborators: make(map[string]string),
+	}
+	for _, team := range teams {
+		permission := stringify(team.Permission)
+		if permission == "" {
+			permission = highestPermission(team.Permissions)
+		}
+		state.Teams[stringify(team.Slug)] = permission
+	}
+	for _, collaborator := range collaborators {
+		permission := stringify(collaborator.RoleName)
+		if permission == "" {
+			permission = highestPermission(collaborator.Permissions)
+		}
+		state.Collaborators[stringify(collaborator.Login)] = permission
+	}
+	return state
+}
+
+func highestPermission(permissions map[string]bool) string {
+	for _, permission := range permissionOrder {
+		if permissions[permission] {
+			return permission
+		}
+	}
+	return ""
+}
+
+// GetState reads the current state of the repository through the lister of
+// the options, which must be the one the state is compared with was read
+// through, since listers report custom roles differently.
+func GetState(ctx context.Context, api *github.Client, org string, repo string, opts *Options) (*State, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	lister := opts.lister(api)
+	teams, err := lister.ListTeams(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	collaborators, err := lister.ListCollaborators(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	return newState(teams, collaborators), nil
+}
+
+// Diff describes how the other state differs from the state, one line per
+// team or collaborator.
+func (s *State) Diff(other *State) []string {
+	var diffs []string
+	diffs = append(diffs, diffPermissions("team", s.Teams, other.Teams)...)
+	diffs = append(diffs, diffPermissions("collaborator", s.Collaborators, other.Collaborators)...)
+	return diffs
+}
+
+func diffPermissions(kind string, before map[string]string, after map[string]string) []string {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []string
+	for _, name := range sorted {
+		b, inBefore := before[name]
+		a, inAfter := after[name]
+		switch {
+		case !inBefore:
+			diffs = append(diffs, fmt.Sprintf("%s %s was added with %s", kind, name, a))
+		case !inAfter:
+			diffs = append(diffs, fmt.Sprintf("%s %s was removed", kind, name))
+		case a != b:
+			diffs = append(diffs, fmt.Sprintf("%s %s changed from %s to %s", kind, name, b, a))
+		}
+	}
+	return diffs
+}
+
+// NewPlan computes the actions for the owners in the CODEOWNERS file and
+// records what they assume.
+func NewPlan(ctx context.Context, api *github.Client, org string, repo string, file *CodeownersFile, opts *Options) (*Plan, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	ruleset, err := file.Ruleset()
+	if err != nil {
+		return nil, err
+	}
+	ruleset, err = opts.expandRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+	actions, state, err := planGrants(ctx, api, org, repo, rulesetOwners(ruleset), opts)
+	if err != nil {
+		return nil, err
+	}
+	actions = annotateRules(actions, ruleset)
+	if actions == nil {
+		actions = []Action{}
+	}
+
+	return &Plan{
+		FormatVersion:  planFormatVersion,
+		CreatedAt:      time.Now().UTC(),
+		Org:            org,
+		Repo:           repo,
+		CodeownersPath: file.Path,
+		CodeownersSHA:  file.SHA,
+		State:          state,
+		Actions:        actions,
+	}, nil
+}
+
+// WritePlanFile saves the plan as JSON.
+func WritePlanFile(path string, plan *Plan) error {
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// ReadPlanFile loads a plan saved by WritePlanFile.
+func ReadPlanFile(path string) (*Plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, err
+	}
+	if plan.FormatVersion != planFormatVersion {
+		return nil, fmt.Errorf("unsupported plan format version: %d", plan.FormatVersion)
+	}
+	return &plan, nil
+}
+
+// StalePlanError is returned when the live state no longer matches what a plan
+// assumed.
+type StalePlanError struct {
+	Reasons []string
+}
+
+func (e *StalePlanError) Error() string {
+	return "the plan is stale:\n  " + strings.Join(e.Reasons, "\n  ")
+}
+
+// VerifyPlan checks that the CODEOWNERS file and the repository state still
+// match what the plan assumed. The state is read with the Backend and Lister
+// of the options, which should be those the plan was made with.
+func VerifyPlan(ctx context.Context, api *github.Client, plan *Plan, file *CodeownersFile, opts *Options) error {
+	var reasons []string
+	if file.SHA != plan.CodeownersSHA {
+		reasons = append(reasons, fmt.Sprintf("%s changed from %s to %s", plan.CodeownersPath, plan.CodeownersSHA, file.SHA))
+	}
+
+	state, err := GetState(ctx, api, plan.Org, plan.Repo, opts)
+	if err != nil {
+		return err
+	}
+	reasons = append(reasons, plan.State.Diff(state)...)
+
+	if len(reasons) > 0 {
+		return &StalePlanError{Reasons: reasons}
+	}
+	return nil
+}
+
+// ApplyPlan verifies the plan and applies its actions. Only the Backend,
+// Lister and Auditor of the options are used, as the plan already decided the
+// grants.
+func ApplyPlan(ctx context.Context, api *github.Client, plan *Plan, file *CodeownersFile, opts *Options) error {
+	if err := VerifyPlan(ctx, api, plan, file, opts); err != nil {
+		return err
+	}
+	ApplyActions(ctx, api, plan.Org, plan.Repo, plan.Actions, opts)
+	return nil
+}
@@ -0,0 +1,138 @@
+package codeownerizer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/go-github/v69/github"
+
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestPlanFile(t *testing.T) {
+	file := &CodeownersFile{
+		Path:    "CODEOWNERS",
+		Content: []byte("* @octocat @octo-org/octocats\n"),
+	}
+	file.SHA = gitBlobSHA(file.Content)
+
+	teams := []github.Team{
+		{
+			Slug:       github.Ptr("octocats"),
+			Permission: github.Ptr("pull"),
+			Permissions: map[string]bool{
+				"pull": true,
+			},
+		},
+	}
+	collaborators := []github.User{
+		{
+			Login: github.Ptr("octocat"),
+			Permissions: map[string]bool{
+				"pull": true,
+				"push": true,
+			},
+		},
+	}
+	// Someone granted the octocats team by hand after the plan was made.
+	changedTeams := []github.Team{
+		{
+			Slug:       github.Ptr("octocats"),
+			Permission: github.Ptr("push"),
+			Permissions: map[string]bool{
+				"pull": true,
+				"push": true,
+			},
+		},
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			teams,
+			teams,
+			changedTeams,
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			collaborators,
+			collaborators,
+			collaborators,
+		),
+	)
+	client := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+
+	plan, err := NewPlan(ctx, client, "org", "repo", file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantState := &State{
+		Teams:         map[string]string{"octocats": "pull"},
+		Collaborators: map[string]string{"octocat": "push"},
+	}
+	if diff := cmp.Diff(wantState, plan.State); diff != "" {
+		t.Errorf("unexpected state\n%s", diff)
+	}
+	wantActions := []Action{
+		{
+			Type:          ActionGrantTeam,
+			Owner:         "@octo-org/octocats",
+			Principal:     "octocats",
+			Permission:    "push",
+			OldPermission: "pull",
+			Rules:         []RuleRef{{Pattern: "*", LineNumber: 1}},
+		},
+	}
+	if diff := cmp.Diff(wantActions, plan.Actions); diff != "" {
+		t.Errorf("unexpected actions\n%s", diff)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlanFile(path, plan); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPlanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(plan, read, cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("unexpected plan read back\n%s", diff)
+	}
+
+	// The state is unchanged.
+	if err := VerifyPlan(ctx, client, read, file, nil); err != nil {
+		t.Errorf("expected the plan to be fresh, got %v", err)
+	}
+
+	// Both the state and CODEOWNERS have changed.
+	changedFile := &CodeownersFile{
+		Path:    "CODEOWNERS",
+		Content: []byte("* @octocat\n"),
+	}
+	changedFile.SHA = gitBlobSHA(changedFile.Content)
+	err = VerifyPlan(ctx, client, read, changedFile, nil)
+	var stale *StalePlanError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected a stale plan error, got %v", err)
+	}
+	wantReasons := []string{
+		"CODEOWNERS changed from " + file.SHA + " to " + changedFile.SHA,
+		"team octocats changed from pull to push",
+	}
+	if diff := cmp.Diff(wantReasons, stale.Reasons); diff != "" {
+		t.Errorf("unexpected reasons\n%s", diff)
+	}
+}
+
+func TestGitBlobSHA(t *testing.T) {
+	// echo 'hello world' | git hash-object --stdin
+	if diff := cmp.Diff("3b18e512dba79e4c8300dd08aeb37f8e728b8dad", gitBlobSHA([]byte("hello world\n"))); diff != "" {
+		t.Errorf("unexpected sha\n%s", diff)
+	}
+}
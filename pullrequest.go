@@ -0,0 +1,94 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// PullRequestOptions describes a pull request changing the CODEOWNERS file.
+type PullRequestOptions struct {
+	// Branch is the head branch. It is reset to the default branch on every
+	// update, so it must only be used by codeownerizer.
+	Branch        string
+	Title         string
+	Body          string
+	CommitMessage string
+}
+
+// UpsertPullRequest commits the new content of the CODEOWNERS file, fetched
+// from the default branch, to the branch, and opens a pull request for it or
+// updates the one already open. The branch is left as is when it already has
+// the new content, so that unchanged runs do not push new commits.
+func UpsertPullRequest(ctx context.Context, api *github.Client, org string, repo string, file *CodeownersFile, content []byte, opts *PullRequestOptions) (*github.PullRequest, error) {
+	repository, _, err := api.Repositories.Get(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	base := repository.GetDefaultBranch()
+
+	current, err := GetCodeownersFile(ctx, api, org, repo, opts.Branch, file.Path)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || current.SHA != gitBlobSHA(content) {
+		baseRef, _, err := api.Git.GetRef(ctx, org, repo, "refs/heads/"+base)
+		if err != nil {
+			return nil, err
+		}
+		if err := resetBranch(ctx, api, org, repo, opts.Branch, baseRef.GetObject().GetSHA()); err != nil {
+			return nil, err
+		}
+
+		if _, _, err := api.Repositories.UpdateFile(ctx, org, repo, file.Path, &github.RepositoryContentFileOptions{
+			Message: github.Ptr(opts.CommitMessage),
+			Content: content,
+			SHA:     github.Ptr(file.SHA),
+			Branch:  github.Ptr(opts.Branch),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	pulls, _, err := api.PullRequests.List(ctx, org, repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  org + ":" + opts.Branch,
+		Base:  base,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pulls) > 0 {
+		pull, _, err := api.PullRequests.Edit(ctx, org, repo, pulls[0].GetNumber(), &github.PullRequest{
+			Title: github.Ptr(opts.Title),
+			Body:  github.Ptr(opts.Body),
+		})
+		return pull, err
+	}
+	pull, _, err := api.PullRequests.Create(ctx, org, repo, &github.NewPullRequest{
+		Title: github.Ptr(opts.Title),
+		Head:  github.Ptr(opts.Branch),
+		Base:  github.Ptr(base),
+		Body:  github.Ptr(opts.Body),
+	})
+	return pull, err
+}
+
+// resetBranch points the branch at the commit, creating it if needed.
+func resetBranch(ctx context.Context, api *github.Client, org string, repo string, branch string, sha string) error {
+	ref := &github.Reference{
+		Ref:    github.Ptr("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: github.Ptr(sha)},
+	}
+	_, resp, err := api.Git.GetRef(ctx, org, repo, "refs/heads/"+branch)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		_, _, err = api.Git.CreateRef(ctx, org, repo, ref)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	_, _, err = api.Git.UpdateRef(ctx, org, repo, ref, true)
+	return err
+}
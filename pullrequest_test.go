@@ -0,0 +1,53 @@
+package codeownerizer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestUpsertPullRequestUnchanged(t *testing.T) {
+	content := []byte("* @org/team1\n")
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposByOwnerByRepo,
+			github.Repository{DefaultBranch: github.Ptr("main")},
+		),
+		// The branch already has the new content, so neither the ref nor the
+		// file is requested or updated.
+		mock.WithRequestMatch(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			github.RepositoryContent{
+				Type:     github.Ptr("file"),
+				Encoding: github.Ptr(""),
+				Content:  github.Ptr(string(content)),
+				SHA:      github.Ptr(gitBlobSHA(content)),
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposPullsByOwnerByRepo,
+			[]github.PullRequest{{Number: github.Ptr(7)}},
+		),
+		mock.WithRequestMatch(
+			mock.PatchReposPullsByOwnerByRepoByPullNumber,
+			github.PullRequest{Number: github.Ptr(7)},
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+
+	file := &CodeownersFile{Path: ".github/CODEOWNERS", SHA: "old", Content: []byte("* @org/gone\n")}
+	pull, err := UpsertPullRequest(context.Background(), c, "org", "repo", file, content, &PullRequestOptions{
+		Branch:        "codeownerizer/update",
+		Title:         "Update CODEOWNERS",
+		CommitMessage: "Update CODEOWNERS",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(7, pull.GetNumber()); diff != "" {
+		t.Errorf("unexpected pull request\n%s", diff)
+	}
+}
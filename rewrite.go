@@ -0,0 +1,62 @@
+package codeownerizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grezar/codeownerizer/codeownersfile"
+)
+
+// RewriteOwners replaces the owners in the CODEOWNERS content according to
+// the replacements, keyed by owner as written in CODEOWNERS. An owner replaced
+// by an empty string is removed, and a rule left without owners is commented
+// out. Owners are matched case-insensitively, and everything else, including
+// comments and alignment, is left as is. Every owner is replaced at most once,
+// so a mapping of a to b and b to c replaces a with b, not with c.
+func RewriteOwners(content []byte, replacements map[string]string) []byte {
+	folded := make(map[string]string, len(replacements))
+	for owner, replacement := range replacements {
+		folded[strings.ToLower(owner)] = replacement
+	}
+	file := codeownersfile.ParseBytes(content)
+	file.MapOwners(func(owner string) string {
+		if replacement, ok := folded[strings.ToLower(owner)]; ok {
+			return replacement
+		}
+		return owner
+	})
+	return file.Bytes()
+}
+
+// ReadOwnerMappingFile reads a mapping of old owners to new owners, one pair
+// per line separated by whitespace. A line with only the old owner maps it to
+// nothing, which removes it. Blank lines and lines starting with # are
+// skipped.
+func ReadOwnerMappingFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mapping := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			mapping[fields[0]] = ""
+		case 2:
+			mapping[fields[0]] = fields[1]
+		default:
+			return nil, fmt.Errorf("%s:%d: expected an old owner and at most one new owner", path, n)
+		}
+	}
+	return mapping, scanner.Err()
+}
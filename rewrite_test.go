@@ -0,0 +1,66 @@
+package codeownerizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRewriteOwners(t *testing.T) {
+	content := "# Owners @old-team\n" +
+		"\n" +
+		"*                @org/Old-Team   @user1 # @org/old-team\n" +
+		"/my\\ docs/\t@org/old-team\t@gone\r\n" +
+		"/src/ @gone\n" +
+		"/bin/ @user1"
+	got := RewriteOwners([]byte(content), map[string]string{
+		"@org/old-team": "@org/new-team",
+		"@gone":         "",
+	})
+	want := "# Owners @old-team\n" +
+		"\n" +
+		"*                @org/new-team   @user1 # @org/old-team\n" +
+		"/my\\ docs/\t@org/new-team\r\n" +
+		"# No owner left: /src/ @gone\n" +
+		"/bin/ @user1"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("unexpected content\n%s", diff)
+	}
+}
+
+func TestRewriteOwnersChained(t *testing.T) {
+	content := "* @a @b\n/docs/ @b\n/src/ @c @a\n"
+	got := RewriteOwners([]byte(content), map[string]string{
+		"@a": "@b",
+		"@b": "@c",
+	})
+	// @a becomes @b and @b becomes @c, whatever the order of the map.
+	want := "* @b @c\n/docs/ @c\n/src/ @c @b\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("unexpected content\n%s", diff)
+	}
+}
+
+func TestReadOwnerMappingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping")
+	if err := os.WriteFile(path, []byte("# old new\n@org/old @org/new\n\n@gone\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := ReadOwnerMappingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"@org/old": "@org/new", "@gone": ""}
+	if diff := cmp.Diff(want, mapping); diff != "" {
+		t.Errorf("unexpected mapping\n%s", diff)
+	}
+
+	if err := os.WriteFile(path, []byte("@a @b @c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadOwnerMappingFile(path); err == nil {
+		t.Error("expected an error for a line with two new owners")
+	}
+}
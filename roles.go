@@ -0,0 +1,137 @@
+package codeownerizer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+// grantPermissions are the base permissions accepted by the grant endpoints,
+// mapped to whether they include the push permission.
+var grantPermissions = map[string]bool{
+	"pull":     false,
+	"triage":   false,
+	"push":     true,
+	"maintain": true,
+	"admin":    true,
+}
+
+// ListCustomRepoRoles lists the custom repository roles of the organization.
+// Organizations on plans without custom roles answer 404, and tokens that
+// cannot read them 403, so both yield no roles, leaving the base permissions.
+func ListCustomRepoRoles(ctx context.Context, api *github.Client, org string) ([]*github.CustomRepoRoles, error) {
+	roles, resp, err := api.Organizations.ListCustomRepoRoles(ctx, org)
+	if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return roles.CustomRepoRoles, nil
+}
+
+// listPushCustomRoles returns the names of the custom repository roles of the
+// organization whose base role includes the push permission. Backends that do
+// not know custom roles yield none, and so do errors, which are logged.
+func listPushCustomRoles(ctx context.Context, backend Backend, org string) map[string]bool {
+	customRoles := make(map[string]bool)
+	roleBackend, ok := backend.(CustomRoleBackend)
+	if !ok {
+		return customRoles
+	}
+	roles, err := roleBackend.ListCustomRepoRoles(ctx, org)
+	if err != nil {
+		log.Println(err.Error())
+		return customRoles
+	}
+	for _, role := range roles {
+		if CanApprove(role) {
+			customRoles[role.GetName()] = true
+		}
+	}
+	return customRoles
+}
+
+// CanApprove reports whether the custom repository role lets code owners
+// approve, that is, whether its base role includes the push permission.
+// Custom roles are based on read, triage, write or maintain.
+func CanApprove(role *github.CustomRepoRoles) bool {
+	return pushRoles[role.GetBaseRole()]
+}
+
+// validatePermission checks that the permission to grant is either a base
+// permission or a custom role that lets code owners approve.
+func validatePermission(permission string, customRoles map[string]bool) error {
+	if push, ok := grantPermissions[permission]; ok {
+		if !push {
+			return fmt.Errorf("the %s permission does not allow code owners to approve, use %s", permission, approvingPermissions(customRoles))
+		}
+		return nil
+	}
+	if !customRoles[permission] {
+		return fmt.Errorf("%s is neither a base permission nor a custom repository role based on write or higher, use %s", permission, approvingPermissions(customRoles))
+	}
+	return nil
+}
+
+// approvingPermissions lists the permissions that let code owners approve,
+// including the custom roles of the organization.
+func approvingPermissions(customRoles map[string]bool) string {
+	var permissions []string
+	for permission, push := range grantPermissions {
+		if push {
+			permissions = append(permissions, permission)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return slices.Index(permissionOrder, permissions[i]) > slices.Index(permissionOrder, permissions[j])
+	})
+	list := strings.Join(permissions, ", ") + " or a custom repository role based on write or higher"
+	if len(customRoles) == 0 {
+		return list
+	}
+	roles := make([]string, 0, len(customRoles))
+	for role := range customRoles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return list + " (" + strings.Join(roles, ", ") + ")"
+}
+
+// applyCustomRoles returns the teams and collaborators with the push permission
+// set on those whose repository role is a custom role based on write or
+// higher, because the Permissions maps only reflect base roles.
+func applyCustomRoles(teams []*github.Team, collaborators []*github.User, customRoles map[string]bool) ([]*github.Team, []*github.User) {
+	if len(customRoles) == 0 {
+		return teams, collaborators
+	}
+
+	appliedTeams := make([]*github.Team, 0, len(teams))
+	for _, team := range teams {
+		if customRoles[stringify(team.Permission)] {
+			team = mergeTeamPermissions(team, map[string]bool{pushPermission: true})
+		}
+		appliedTeams = append(appliedTeams, team)
+	}
+
+	appliedCollaborators := make([]*github.User, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		if customRoles[stringify(collaborator.RoleName)] {
+			c := *collaborator
+			c.Permissions = map[string]bool{pushPermission: true}
+			for k, v := range collaborator.Permissions {
+				c.Permissions[k] = c.Permissions[k] || v
+			}
+			collaborator = &c
+		}
+		appliedCollaborators = append(appliedCollaborators, collaborator)
+	}
+
+	return appliedTeams, appliedCollaborators
+}
@@ -0,0 +1,81 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestListCustomRepoRoles(t *testing.T) {
+	for name, status := range map[string]int{
+		"not found": http.StatusNotFound,
+		"forbidden": http.StatusForbidden,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockedHTTPClient := mock.NewMockedHTTPClient(
+				mock.WithRequestMatchHandler(
+					mock.GetOrgsCustomRepositoryRolesByOrg,
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						mock.WriteError(w, status, "custom repository roles are not available")
+					}),
+				),
+			)
+			roles, err := ListCustomRepoRoles(context.Background(), github.NewClient(mockedHTTPClient), "org")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(roles) != 0 {
+				t.Errorf("expected no roles, got %v", roles)
+			}
+		})
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsCustomRepositoryRolesByOrg,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mock.WriteError(w, http.StatusInternalServerError, "server error")
+			}),
+		),
+	)
+	if _, err := ListCustomRepoRoles(context.Background(), github.NewClient(mockedHTTPClient), "org"); err == nil {
+		t.Error("expected an error for a server error")
+	}
+}
+
+func TestCanApprove(t *testing.T) {
+	got := map[string]bool{}
+	for _, base := range []string{"read", "triage", "write", "maintain"} {
+		got[base] = CanApprove(&github.CustomRepoRoles{BaseRole: github.Ptr(base)})
+	}
+	want := map[string]bool{"read": false, "triage": false, "write": true, "maintain": true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected approvals\n%s", diff)
+	}
+}
+
+func TestValidatePermission(t *testing.T) {
+	customRoles := map[string]bool{"code-reviewer": true, "approver": true}
+	tests := []struct {
+		permission string
+		want       string
+	}{
+		{"push", ""},
+		{"approver", ""},
+		{"triage", "the triage permission does not allow code owners to approve, use push, maintain, admin or a custom repository role based on write or higher (approver, code-reviewer)"},
+		{"triager", "triager is neither a base permission nor a custom repository role based on write or higher, use push, maintain, admin or a custom repository role based on write or higher (approver, code-reviewer)"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := validatePermission(tt.permission, customRoles); err != nil {
+			got = err.Error()
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("unexpected error for %s\n%s", tt.permission, diff)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package codeownerizer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// RuleReport describes whether a CODEOWNERS rule can be approved by at least
+// one of its owners.
+type RuleReport struct {
+	Pattern    string        `json:"pattern"`
+	LineNumber int           `json:"line_number"`
+	Owners     []OwnerReport `json:"owners"`
+	// Approvable is true when at least one owner of the rule can approve.
+	Approvable bool `json:"approvable"`
+	// Blocked is true when the rule has owners but none of them can approve,
+	// which silently blocks pull requests touching the matching files.
+	Blocked bool `json:"blocked"`
+}
+
+// OwnerReport describes whether an owner can approve reviews on the repository.
+type OwnerReport struct {
+	Owner      string `json:"owner"`
+	CanApprove bool   `json:"can_approve"`
+	// Reason explains why the owner cannot approve.
+	Reason string `json:"reason,omitempty"`
+	// Memberless is true for a team without active members, that is, a team
+	// that is empty or whose members are all suspended users or bots.
+	Memberless bool `json:"memberless,omitempty"`
+}
+
+// AnalyzeRules reports, for every rule in the ruleset, which owners can
+// actually approve reviews on the repository. An owner can approve when it
+// has the push permission, and a team owner additionally needs active members.
+func AnalyzeRules(ctx context.Context, api *github.Client, org string, repo string, ruleset codeowners.Ruleset, opts *Options) ([]RuleReport, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	ruleset, err := opts.expandRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := opts.backend(api)
+	lister := opts.lister(api)
+
+	teams, err := lister.ListTeams(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	teams = inheritTeamPermissions(ctx, backend, org, teams)
+
+	collaborators, err := lister.ListCollaborators(ctx, org, repo)
+	if err != nil {
+		return nil, err
+	}
+	teams, collaborators = applyCustomRoles(teams, collaborators, listPushCustomRoles(ctx, backend, org))
+
+	emails := resolveEmailOwners(ctx, lister, rulesetOwners(ruleset))
+
+	// Owners usually appear on many rules, so each of them is only checked once.
+	analyzed := make(map[string]OwnerReport)
+
+	reports := make([]RuleReport, 0, len(ruleset))
+	for _, rule := range ruleset {
+		report := RuleReport{
+			Pattern:    rule.RawPattern(),
+			LineNumber: rule.LineNumber,
+			Owners:     []OwnerReport{},
+		}
+		for _, owner := range rule.Owners {
+			ownerReport, ok := analyzed[owner.String()]
+			if !ok {
+				ownerReport = analyzeOwner(ctx, backend, org, repo, teams, collaborators, emails, owner, opts)
+				analyzed[owner.String()] = ownerReport
+			}
+			report.Owners = append(report.Owners, ownerReport)
+			if ownerReport.CanApprove {
+				report.Approvable = true
+			}
+		}
+		report.Blocked = len(rule.Owners) > 0 && !report.Approvable
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// BlockedRules returns the rules that none of their owners can approve.
+func BlockedRules(reports []RuleReport) []RuleReport {
+	var blocked []RuleReport
+	for _, report := range reports {
+		if report.Blocked {
+			blocked = append(blocked, report)
+		}
+	}
+	return blocked
+}
+
+// MemberlessRules returns the rules that are owned only by teams without
+// active members. Granting the push permission to such teams satisfies the
+// permission check, but no one can approve reviews on their behalf.
+func MemberlessRules(reports []RuleReport) []RuleReport {
+	var memberless []RuleReport
+	for _, report := range reports {
+		if len(report.Owners) == 0 {
+			continue
+		}
+		onlyMemberless := true
+		for _, owner := range report.Owners {
+			if !owner.Memberless {
+				onlyMemberless = false
+				break
+			}
+		}
+		if onlyMemberless {
+			memberless = append(memberless, report)
+		}
+	}
+	return memberless
+}
+
+func analyzeOwner(ctx context.Context, backend Backend, org string, repo string, teams []*github.Team, collaborators []*github.User, emails map[string]emailOwner, owner codeowners.Owner, opts *Options) OwnerReport {
+	report := OwnerReport{Owner: owner.String()}
+
+	switch owner.Type {
+	case codeowners.TeamOwner:
+		teamOwnerName := strings.Split(owner.String(), "/")[1]
+		ok, err := teamHasPushPermission(ctx, backend, org, repo, teams, teamOwnerName, opts)
+		if err != nil {
+			report.Reason = err.Error()
+			return report
+		}
+		if !ok {
+			report.Reason = "team does not have the push permission"
+			return report
+		}
+		membersBackend, ok := backend.(TeamMembersBackend)
+		if !ok {
+			break
+		}
+		// Members of child teams are listed as members of the team as well.
+		members, err := membersBackend.ListTeamMembers(ctx, org, teamOwnerName)
+		if err != nil {
+			report.Reason = err.Error()
+			return report
+		}
+		if len(members) == 0 {
+			report.Reason = "team has no members"
+			report.Memberless = true
+			return report
+		}
+		active, err := hasActiveMember(ctx, membersBackend, members)
+		if err != nil {
+			report.Reason = err.Error()
+			return report
+		}
+		if !active {
+			report.Reason = "team has no active members"
+			report.Memberless = true
+			return report
+		}
+	case codeowners.UsernameOwner:
+		userOwnerName := strings.TrimPrefix(owner.String(), "@")
+		ok, err := userHasPushPermission(ctx, backend, org, repo, collaborators, userOwnerName, opts)
+		if err != nil {
+			report.Reason = err.Error()
+			return report
+		}
+		if !ok {
+			report.Reason = "user does not have the push permission"
+			return report
+		}
+	case codeowners.EmailOwner:
+		email := emails[owner.String()]
+		if email.err != nil {
+			report.Reason = email.err.Error()
+			return report
+		}
+		ok, err := userHasPushPermission(ctx, backend, org, repo, collaborators, email.login, opts)
+		if err != nil {
+			report.Reason = err.Error()
+			return report
+		}
+		if !ok {
+			report.Reason = "user does not have the push permission"
+			return report
+		}
+	default:
+		report.Reason = "unknown owner type: " + owner.Type
+		return report
+	}
+
+	report.CanApprove = true
+	return report
+}
+
+// hasActiveMember reports whether any of the members is neither a bot nor a
+// suspended user. Team member lists do not include the suspension state, so
+// users are looked up one by one until an active one is found.
+func hasActiveMember(ctx context.Context, backend TeamMembersBackend, members []*github.User) (bool, error) {
+	for _, member := range members {
+		if member.GetType() == "Bot" {
+			continue
+		}
+		user, err := backend.GetUser(ctx, member.GetLogin())
+		if err != nil {
+			return false, err
+		}
+		if user.SuspendedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,187 @@
+package codeownerizer
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestAnalyzeRules(t *testing.T) {
+	ruleset, err := codeowners.LoadFile("testdata/CODEOWNERS-RULES")
+	if err != nil {
+		t.Error(err)
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				{
+					Slug: github.Ptr("octocats"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+				{
+					Slug: github.Ptr("empty"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{
+				{
+					Login: github.Ptr("octocat"),
+					Permissions: map[string]bool{
+						"push": true,
+					},
+				},
+				{
+					Login: github.Ptr("doctocat"),
+					Permissions: map[string]bool{
+						"push": false,
+					},
+				},
+			},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsTeamsMembersByOrgByTeamSlug,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				members := []github.User{}
+				if strings.Contains(r.URL.Path, "/teams/octocats/") {
+					members = append(members, github.User{Login: github.Ptr("octocat")})
+				}
+				_, _ = w.Write(mock.MustMarshal(members))
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.GetUsersByUsername,
+			github.User{Login: github.Ptr("octocat")},
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	reports, err := AnalyzeRules(context.Background(), client, "org", "repo", ruleset, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	want := []RuleReport{
+		{
+			Pattern:    "*",
+			LineNumber: 2,
+			Owners: []OwnerReport{
+				{Owner: "@octo-org/octocats", CanApprove: true},
+			},
+			Approvable: true,
+		},
+		{
+			Pattern:    "*.go",
+			LineNumber: 5,
+			Owners: []OwnerReport{
+				{Owner: "@octo-org/empty", Reason: "team has no members", Memberless: true},
+				{Owner: "@doctocat", Reason: "user does not have the push permission"},
+			},
+			Blocked: true,
+		},
+		{
+			Pattern:    "/docs/",
+			LineNumber: 7,
+			Owners: []OwnerReport{
+				{Owner: "@octocat", CanApprove: true},
+			},
+			Approvable: true,
+		},
+		{
+			Pattern:    "/apps/github",
+			LineNumber: 10,
+			Owners:     []OwnerReport{},
+		},
+	}
+	if diff := cmp.Diff(want, reports); diff != "" {
+		t.Errorf("unexpected reports\n%s", diff)
+	}
+
+	if diff := cmp.Diff(want[1:2], BlockedRules(reports)); diff != "" {
+		t.Errorf("unexpected blocked rules\n%s", diff)
+	}
+}
+
+func TestAnalyzeRulesWithMemberlessTeams(t *testing.T) {
+	ruleset, err := codeowners.ParseFile(strings.NewReader(`/bots/ @octo-org/bots
+/suspended/ @octo-org/suspended @octo-org/bots
+/mixed/ @octo-org/bots @octo-org/humans
+`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetReposTeamsByOwnerByRepo,
+			[]github.Team{
+				{Slug: github.Ptr("bots"), Permissions: map[string]bool{"push": true}},
+				{Slug: github.Ptr("suspended"), Permissions: map[string]bool{"push": true}},
+				{Slug: github.Ptr("humans"), Permissions: map[string]bool{"push": true}},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposCollaboratorsByOwnerByRepo,
+			[]github.User{},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsTeamsMembersByOrgByTeamSlug,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var members []github.User
+				switch {
+				case strings.Contains(r.URL.Path, "/teams/bots/"):
+					members = []github.User{{Login: github.Ptr("dependabot"), Type: github.Ptr("Bot")}}
+				case strings.Contains(r.URL.Path, "/teams/suspended/"):
+					members = []github.User{{Login: github.Ptr("former"), Type: github.Ptr("User")}}
+				case strings.Contains(r.URL.Path, "/teams/humans/"):
+					members = []github.User{{Login: github.Ptr("octocat"), Type: github.Ptr("User")}}
+				}
+				_, _ = w.Write(mock.MustMarshal(members))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetUsersByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user := github.User{Login: github.Ptr(path.Base(r.URL.Path))}
+				if user.GetLogin() == "former" {
+					user.SuspendedAt = &github.Timestamp{}
+				}
+				_, _ = w.Write(mock.MustMarshal(user))
+			}),
+		),
+	)
+
+	client := github.NewClient(mockedHTTPClient)
+	reports, err := AnalyzeRules(context.Background(), client, "org", "repo", ruleset, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var memberless []string
+	for _, report := range MemberlessRules(reports) {
+		memberless = append(memberless, report.Pattern)
+	}
+	if diff := cmp.Diff([]string{"/bots/", "/suspended/"}, memberless); diff != "" {
+		t.Errorf("unexpected memberless rules\n%s", diff)
+	}
+
+	if diff := cmp.Diff("team has no active members", reports[1].Owners[0].Reason); diff != "" {
+		t.Errorf("unexpected reason\n%s", diff)
+	}
+}
@@ -0,0 +1,408 @@
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// ReconcileFunc reconciles the permissions of the code owners of a repository.
+type ReconcileFunc func(ctx context.Context, org string, repo string) error
+
+// NewRepositoryReconciler returns a ReconcileFunc that fetches the CODEOWNERS
+// file from the default branch of the repository and grants its owners. The
+// owners are recorded in the cache, which may be nil.
+func NewRepositoryReconciler(api *github.Client, opts *Options, cache *OwnerCache) ReconcileFunc {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return func(ctx context.Context, org string, repo string) error {
+		file, err := FetchCodeownersFile(ctx, api, org, repo, "")
+		if err != nil {
+			return err
+		}
+		ruleset, err := file.Ruleset()
+		if err != nil {
+			return err
+		}
+		if cache != nil {
+			expanded, err := opts.expandRuleset(ruleset)
+			if err != nil {
+				return err
+			}
+			owners := rulesetOwners(expanded)
+			// Access through the codeowners team counts as well.
+			if opts.CodeownersTeam != "" {
+				owners = append(owners, codeowners.Owner{Value: org + "/" + opts.CodeownersTeam, Type: codeowners.TeamOwner})
+			}
+			cache.Set(org, repo, owners)
+		}
+		return Reconcile(ctx, api, org, repo, ruleset, opts)
+	}
+}
+
+// OwnerCache remembers the owners listed in the CODEOWNERS file of each
+// repository when it was last reconciled, so that webhooks about teams and
+// users that own nothing in a repository do not reconcile it.
+type OwnerCache struct {
+	mu     sync.Mutex
+	owners map[string]map[string]bool
+}
+
+func NewOwnerCache() *OwnerCache {
+	return &OwnerCache{owners: make(map[string]map[string]bool)}
+}
+
+// Set records the owners of the repository.
+func (c *OwnerCache) Set(org string, repo string, owners []codeowners.Owner) {
+	set := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		set[strings.ToLower(owner.String())] = true
+		// Email owners may stand for any user.
+		if owner.Type == codeowners.EmailOwner {
+			set[anyUser] = true
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.owners[repoKey(org, repo)] = set
+}
+
+// anyUser is recorded for repositories whose owners include an email, which
+// is only resolved to a user when reconciling.
+const anyUser = "@*"
+
+// mayOwn reports whether the owner, as written in CODEOWNERS, may be an owner
+// of the repository. Repositories that were never reconciled may have any
+// owner.
+func (c *OwnerCache) mayOwn(org string, repo string, owner string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owners, ok := c.owners[repoKey(org, repo)]
+	if !ok {
+		return true
+	}
+	if owners[strings.ToLower(owner)] {
+		return true
+	}
+	return !strings.Contains(owner, "/") && owners[anyUser]
+}
+
+// hasOwners reports whether the repository may have owners. Repositories that
+// were never reconciled may.
+func (c *OwnerCache) hasOwners(org string, repo string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owners, ok := c.owners[repoKey(org, repo)]
+	return !ok || len(owners) > 0
+}
+
+// repos lists the repositories owned by the owner, as org/repo, sorted.
+func (c *OwnerCache) repos(owner string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var repos []string
+	for key, owners := range c.owners {
+		if owners[strings.ToLower(owner)] {
+			repos = append(repos, key)
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+func repoKey(org string, repo string) string {
+	return strings.ToLower(org + "/" + repo)
+}
+
+// TeamRenamedFunc handles the rename of a team from the old slug to the new one.
+type TeamRenamedFunc func(ctx context.Context, org string, oldSlug string, newSlug string) error
+
+// Server reconciles repositories in response to GitHub webhooks. Requests are
+// only accepted with a valid X-Hub-Signature-256 signature, and the work
+// happens in the background, one job at a time.
+type Server struct {
+	// OnTeamRenamed is called when a team is renamed, which changes its slug
+	// and leaves CODEOWNERS entries using the old slug stale. It is optional.
+	OnTeamRenamed TeamRenamedFunc
+	// Owners filters the events about teams, users and repositories down to
+	// those that can affect the access of the code owners. It should be the
+	// cache the ReconcileFunc fills. Without it, every such event reconciles
+	// the repository.
+	Owners *OwnerCache
+
+	secret    []byte
+	reconcile ReconcileFunc
+	queue     *Queue
+	mux       *http.ServeMux
+}
+
+// NewServer returns a server verifying webhooks with the secret.
+func NewServer(secret []byte, reconcile ReconcileFunc) *Server {
+	s := &Server{
+		secret:    secret,
+		reconcile: reconcile,
+		queue:     NewQueue(),
+		mux:       http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/webhook", s.handleWebhook)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Run runs the queued jobs until the context is done.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		job, ok := s.queue.Dequeue(ctx)
+		if !ok {
+			return
+		}
+		if err := job.Run(ctx); err != nil {
+			log.Printf("%s failed: %s\n", job.Key, err.Error())
+		}
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status": "ok",
+		"queued": s.queue.Len(),
+	})
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPayloadBytes)
+	payload, err := github.ValidatePayload(r, s.secret)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch event := event.(type) {
+	case *github.PushEvent:
+		if touchesCodeowners(event) {
+			s.enqueueReconcile(event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName())
+		}
+	case *github.TeamAddEvent:
+		// A team was given access to a repository.
+		org, repo := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+		if s.mayOwn(org, repo, "@"+org+"/"+event.GetTeam().GetSlug()) {
+			s.enqueueReconcile(org, repo)
+		}
+	case *github.TeamEvent:
+		s.handleTeamEvent(event)
+	case *github.MemberEvent:
+		// A collaborator was added to, removed from, or changed on a repository.
+		org, repo := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+		if s.mayOwn(org, repo, "@"+event.GetMember().GetLogin()) {
+			s.enqueueReconcile(org, repo)
+		}
+	case *github.RepositoryEvent:
+		org, repo := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+		if reconciledRepositoryActions[event.GetAction()] && (s.Owners == nil || s.Owners.hasOwners(org, repo)) {
+			s.enqueueReconcile(org, repo)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// reconciledRepositoryActions are the repository event actions after which
+// the CODEOWNERS of the repository may lack access.
+var reconciledRepositoryActions = map[string]bool{
+	"created":     true,
+	"renamed":     true,
+	"transferred": true,
+	"unarchived":  true,
+}
+
+func (s *Server) handleTeamEvent(event *github.TeamEvent) {
+	org := event.GetOrg().GetLogin()
+
+	// Changes to the access of the team on a repository come with the
+	// repository.
+	if event.Repo != nil {
+		repoOrg, repo := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+		if s.mayOwn(repoOrg, repo, "@"+org+"/"+event.GetTeam().GetSlug()) {
+			s.enqueueReconcile(repoOrg, repo)
+		}
+	}
+
+	switch event.GetAction() {
+	case "edited":
+		if event.GetChanges().GetName() == nil || s.OnTeamRenamed == nil {
+			return
+		}
+		oldSlug := TeamSlug(event.GetChanges().GetName().GetFrom())
+		newSlug := event.GetTeam().GetSlug()
+		if oldSlug == newSlug {
+			return
+		}
+		s.enqueue(Job{
+			Key: "rename " + org + "/" + oldSlug + " " + newSlug,
+			Run: func(ctx context.Context) error {
+				return s.OnTeamRenamed(ctx, org, oldSlug, newSlug)
+			},
+		})
+	case "deleted":
+		owner := "@" + org + "/" + event.GetTeam().GetSlug()
+		log.Printf("team %s was deleted, so CODEOWNERS entries using it are stale\n", owner)
+		// Members of the team may have lost their access through it.
+		if s.Owners != nil {
+			for _, key := range s.Owners.repos(owner) {
+				repoOrg, repo, _ := strings.Cut(key, "/")
+				s.enqueueReconcile(repoOrg, repo)
+			}
+		}
+	}
+}
+
+// mayOwn reports whether the owner may be an owner of the repository, which
+// is always the case without an owner cache.
+func (s *Server) mayOwn(org string, repo string, owner string) bool {
+	return s.Owners == nil || s.Owners.mayOwn(org, repo, owner)
+}
+
+func (s *Server) enqueueReconcile(org string, repo string) {
+	s.enqueue(Job{
+		Key: "reconcile " + org + "/" + repo,
+		Run: func(ctx context.Context) error {
+			return s.reconcile(ctx, org, repo)
+		},
+	})
+}
+
+func (s *Server) enqueue(job Job) {
+	if s.queue.Enqueue(job) {
+		log.Printf("%s was queued\n", job.Key)
+	}
+}
+
+// maxPushCommits is the number of commits GitHub lists at most in a push
+// event.
+const maxPushCommits = 20
+
+// maxPayloadBytes is the size of the largest webhook payload GitHub sends.
+const maxPayloadBytes = 25 << 20
+
+// touchesCodeowners reports whether the push to the default branch added,
+// modified or removed a CODEOWNERS file. Force pushes and pushes whose commits
+// are not all listed may touch it without saying so.
+func touchesCodeowners(event *github.PushEvent) bool {
+	if event.GetRef() != "refs/heads/"+event.GetRepo().GetDefaultBranch() || event.GetDeleted() {
+		return false
+	}
+	if event.GetForced() || len(event.Commits) == 0 || len(event.Commits) >= maxPushCommits || event.GetSize() > len(event.Commits) {
+		return true
+	}
+	for _, commit := range event.Commits {
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, file := range files {
+				for _, location := range CodeownersLocations {
+					if file == location {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Job is a unit of work run by the server.
+type Job struct {
+	// Key identifies the work, such as reconciling a particular repository.
+	Key string
+	Run func(ctx context.Context) error
+}
+
+// Queue is a FIFO queue of jobs in which each key is queued at most once. A
+// key is queued again only after its job has been dequeued, so bursts of
+// events for the same repository cause a single reconcile.
+type Queue struct {
+	mu      sync.Mutex
+	jobs    []Job
+	pending map[string]bool
+	ready   chan struct{}
+}
+
+func NewQueue() *Queue {
+	return &Queue{
+		pending: make(map[string]bool),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue adds the job unless a job with the same key is already queued, and
+// reports whether it was added.
+func (q *Queue) Enqueue(job Job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[job.Key] {
+		return false
+	}
+	q.pending[job.Key] = true
+	q.jobs = append(q.jobs, job)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// Dequeue waits for a job until the context is done.
+func (q *Queue) Dequeue(ctx context.Context) (Job, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.jobs) > 0 {
+			job := q.jobs[0]
+			q.jobs = q.jobs[1:]
+			delete(q.pending, job.Key)
+			q.mu.Unlock()
+			return job, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Job{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+// Len returns the number of queued jobs.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
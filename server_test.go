@@ -0,0 +1,359 @@
+package codeownerizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+var testWebhookSecret = []byte("secret")
+
+func newWebhookRequest(t *testing.T, eventType string, event any, secret []byte) *http.Request {
+	t.Helper()
+	payload := mock.MustMarshal(event)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", eventType)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func newPushEvent(repo string, ref string, modified ...string) github.PushEvent {
+	return github.PushEvent{
+		Ref: github.Ptr(ref),
+		Repo: &github.PushEventRepository{
+			Name:          github.Ptr(repo),
+			DefaultBranch: github.Ptr("main"),
+			Owner:         &github.User{Login: github.Ptr("org")},
+		},
+		Commits: []*github.HeadCommit{
+			{Modified: modified},
+		},
+	}
+}
+
+func TestServerWithPushEvent(t *testing.T) {
+	var mu sync.Mutex
+	var reconciled []string
+	done := make(chan struct{}, 10)
+	server := NewServer(testWebhookSecret, func(ctx context.Context, org string, repo string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		reconciled = append(reconciled, org+"/"+repo)
+		done <- struct{}{}
+		return nil
+	})
+
+	truncatedPushEvent := newPushEvent("repo6", "refs/heads/main", "README.md")
+	truncatedPushEvent.Size = github.Ptr(maxPushCommits + 1)
+	forcedPushEvent := newPushEvent("repo7", "refs/heads/main")
+	forcedPushEvent.Forced = github.Ptr(true)
+	oversizedRequest := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(make([]byte, maxPayloadBytes+1)))
+	oversizedRequest.Header.Set("Content-Type", "application/json")
+	oversizedRequest.Header.Set("X-GitHub-Event", "push")
+
+	requests := []struct {
+		req  *http.Request
+		code int
+	}{
+		// CODEOWNERS changed on the default branch.
+		{newWebhookRequest(t, "push", newPushEvent("repo1", "refs/heads/main", ".github/CODEOWNERS"), testWebhookSecret), http.StatusAccepted},
+		// Deduplicated while repo1 is queued.
+		{newWebhookRequest(t, "push", newPushEvent("repo1", "refs/heads/main", "CODEOWNERS"), testWebhookSecret), http.StatusAccepted},
+		// Another branch.
+		{newWebhookRequest(t, "push", newPushEvent("repo2", "refs/heads/feature", ".github/CODEOWNERS"), testWebhookSecret), http.StatusAccepted},
+		// Other files.
+		{newWebhookRequest(t, "push", newPushEvent("repo3", "refs/heads/main", "README.md"), testWebhookSecret), http.StatusAccepted},
+		// Invalid signature.
+		{newWebhookRequest(t, "push", newPushEvent("repo4", "refs/heads/main", "CODEOWNERS"), []byte("wrong")), http.StatusUnauthorized},
+		{newWebhookRequest(t, "push", newPushEvent("repo5", "refs/heads/main", "docs/CODEOWNERS"), testWebhookSecret), http.StatusAccepted},
+		// Commits beyond the listed ones may touch CODEOWNERS.
+		{newWebhookRequest(t, "push", truncatedPushEvent, testWebhookSecret), http.StatusAccepted},
+		// So may a force push.
+		{newWebhookRequest(t, "push", forcedPushEvent, testWebhookSecret), http.StatusAccepted},
+		// Payloads larger than GitHub sends are not read.
+		{oversizedRequest, http.StatusRequestEntityTooLarge},
+	}
+	for i, r := range requests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, r.req)
+		if rec.Code != r.code {
+			t.Errorf("request %d: expected status %d, got %d", i, r.code, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if diff := cmp.Diff(`{"queued":4,"status":"ok"}`+"\n", rec.Body.String()); diff != "" {
+		t.Errorf("unexpected health\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Run(ctx)
+	for i := 0; i < 4; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for reconcile")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff([]string{"org/repo1", "org/repo5", "org/repo6", "org/repo7"}, reconciled); diff != "" {
+		t.Errorf("unexpected reconciled repositories\n%s", diff)
+	}
+}
+
+func TestServerWithTeamEvents(t *testing.T) {
+	var mu sync.Mutex
+	var handled []string
+	done := make(chan struct{}, 10)
+	record := func(item string) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, item)
+		done <- struct{}{}
+	}
+	server := NewServer(testWebhookSecret, func(ctx context.Context, org string, repo string) error {
+		record(org + "/" + repo)
+		return nil
+	})
+	server.OnTeamRenamed = func(ctx context.Context, org string, oldSlug string, newSlug string) error {
+		record(org + "/" + oldSlug + " -> " + newSlug)
+		return nil
+	}
+
+	repo := func(name string) *github.Repository {
+		return &github.Repository{Name: github.Ptr(name), Owner: &github.User{Login: github.Ptr("org")}}
+	}
+	owner := &github.Organization{Login: github.Ptr("org")}
+	requests := []*http.Request{
+		newWebhookRequest(t, "team_add", github.TeamAddEvent{Repo: repo("repo1")}, testWebhookSecret),
+		newWebhookRequest(t, "team", github.TeamEvent{
+			Action: github.Ptr("removed_from_repository"),
+			Team:   &github.Team{Slug: github.Ptr("team1")},
+			Repo:   repo("repo2"),
+			Org:    owner,
+		}, testWebhookSecret),
+		newWebhookRequest(t, "team", github.TeamEvent{
+			Action: github.Ptr("edited"),
+			Team:   &github.Team{Slug: github.Ptr("new-team")},
+			Changes: &github.TeamChange{
+				Name: &github.TeamName{From: github.Ptr("Old Team")},
+			},
+			Org: owner,
+		}, testWebhookSecret),
+		// The description changed, not the slug.
+		newWebhookRequest(t, "team", github.TeamEvent{
+			Action: github.Ptr("edited"),
+			Team:   &github.Team{Slug: github.Ptr("team1")},
+			Changes: &github.TeamChange{
+				Name: &github.TeamName{From: github.Ptr("team1")},
+			},
+			Org: owner,
+		}, testWebhookSecret),
+		newWebhookRequest(t, "member", github.MemberEvent{Action: github.Ptr("removed"), Repo: repo("repo3")}, testWebhookSecret),
+		newWebhookRequest(t, "repository", github.RepositoryEvent{Action: github.Ptr("renamed"), Repo: repo("repo4")}, testWebhookSecret),
+		newWebhookRequest(t, "repository", github.RepositoryEvent{Action: github.Ptr("archived"), Repo: repo("repo5")}, testWebhookSecret),
+	}
+	for i, req := range requests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("request %d: expected status %d, got %d", i, http.StatusAccepted, rec.Code)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Run(ctx)
+	for i := 0; i < 5; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for jobs")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"org/repo1", "org/repo2", "org/old-team -> new-team", "org/repo3", "org/repo4"}
+	if diff := cmp.Diff(want, handled); diff != "" {
+		t.Errorf("unexpected jobs\n%s", diff)
+	}
+}
+
+func TestServerWithOwnerCache(t *testing.T) {
+	var mu sync.Mutex
+	var reconciled []string
+	done := make(chan struct{}, 10)
+	server := NewServer(testWebhookSecret, func(ctx context.Context, org string, repo string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		reconciled = append(reconciled, org+"/"+repo)
+		done <- struct{}{}
+		return nil
+	})
+	server.Owners = NewOwnerCache()
+	parse := func(content string) []codeowners.Owner {
+		ruleset, err := codeowners.ParseFile(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rulesetOwners(ruleset)
+	}
+	server.Owners.Set("org", "repo1", parse("* @org/team1 @user1\n"))
+	server.Owners.Set("org", "repo2", parse("* @org/team2\n/docs/ docs@example.com\n"))
+	server.Owners.Set("org", "repo3", nil)
+
+	repo := func(name string) *github.Repository {
+		return &github.Repository{Name: github.Ptr(name), Owner: &github.User{Login: github.Ptr("org")}}
+	}
+	team := func(slug string) *github.Team {
+		return &github.Team{Slug: github.Ptr(slug)}
+	}
+	owner := &github.Organization{Login: github.Ptr("org")}
+	requests := []*http.Request{
+		newWebhookRequest(t, "team_add", github.TeamAddEvent{Team: team("team1"), Repo: repo("repo1")}, testWebhookSecret),
+		// team1 owns nothing in repo2.
+		newWebhookRequest(t, "team_add", github.TeamAddEvent{Team: team("team1"), Repo: repo("repo2")}, testWebhookSecret),
+		newWebhookRequest(t, "team", github.TeamEvent{Action: github.Ptr("removed_from_repository"), Team: team("team1"), Repo: repo("repo2"), Org: owner}, testWebhookSecret),
+		newWebhookRequest(t, "member", github.MemberEvent{Action: github.Ptr("removed"), Member: &github.User{Login: github.Ptr("user2")}, Repo: repo("repo1")}, testWebhookSecret),
+		// The email owner of repo2 may be any user.
+		newWebhookRequest(t, "member", github.MemberEvent{Action: github.Ptr("removed"), Member: &github.User{Login: github.Ptr("user2")}, Repo: repo("repo2")}, testWebhookSecret),
+		// repo3 has no owners, and repo4 was never reconciled.
+		newWebhookRequest(t, "repository", github.RepositoryEvent{Action: github.Ptr("unarchived"), Repo: repo("repo3")}, testWebhookSecret),
+		newWebhookRequest(t, "team_add", github.TeamAddEvent{Team: team("team3"), Repo: repo("repo4")}, testWebhookSecret),
+		// The members of the deleted team2 may have lost their access to repo2.
+		newWebhookRequest(t, "team", github.TeamEvent{Action: github.Ptr("deleted"), Team: team("team2"), Org: owner}, testWebhookSecret),
+	}
+	for i, req := range requests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("request %d: expected status %d, got %d", i, http.StatusAccepted, rec.Code)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Run(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for reconcile")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// repo2 is queued once for the member and the deleted team.
+	if diff := cmp.Diff([]string{"org/repo1", "org/repo2", "org/repo4"}, reconciled); diff != "" {
+		t.Errorf("unexpected reconciled repositories\n%s", diff)
+	}
+}
+
+func TestFindStaleTeamEntries(t *testing.T) {
+	content := "* @org/old-team @user1\n/docs/ @org/new-team\n"
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatch(
+			mock.GetOrgsTeamsReposByOrgByTeamSlug,
+			[]github.Repository{
+				{
+					Name:     github.Ptr("repo"),
+					FullName: github.Ptr("org/repo"),
+					Owner:    &github.User{Login: github.Ptr("org")},
+				},
+			},
+		),
+		mock.WithRequestMatch(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			github.RepositoryContent{
+				Type:     github.Ptr("file"),
+				Encoding: github.Ptr(""),
+				Content:  github.Ptr(content),
+				SHA:      github.Ptr("sha"),
+			},
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+
+	entries, err := FindStaleTeamEntries(context.Background(), c, "org", "old-team", "new-team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StaleEntry{
+		{
+			Repo:        "org/repo",
+			Path:        ".github/CODEOWNERS",
+			LineNumber:  1,
+			Pattern:     "*",
+			Owner:       "@org/old-team",
+			Replacement: "@org/new-team",
+		},
+	}
+	if diff := cmp.Diff(want, entries); diff != "" {
+		t.Errorf("unexpected stale entries\n%s", diff)
+	}
+}
+
+func TestTeamSlug(t *testing.T) {
+	for name, want := range map[string]string{
+		"Platform Team":   "platform-team",
+		"team1":           "team1",
+		"  SRE / On-call": "sre-on-call",
+	} {
+		if got := TeamSlug(name); got != want {
+			t.Errorf("TeamSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	job := func(key string) Job {
+		return Job{Key: key, Run: func(ctx context.Context) error { return nil }}
+	}
+	q := NewQueue()
+	if !q.Enqueue(job("org/a")) || q.Enqueue(job("org/a")) || !q.Enqueue(job("org/b")) {
+		t.Error("expected org/a to be queued once")
+	}
+
+	ctx := context.Background()
+	item, _ := q.Dequeue(ctx)
+	if item.Key != "org/a" {
+		t.Errorf("expected org/a, got %s", item.Key)
+	}
+	// org/a can be queued again once dequeued.
+	if !q.Enqueue(job("org/a")) {
+		t.Error("expected org/a to be queued again")
+	}
+	if diff := cmp.Diff(2, q.Len()); diff != "" {
+		t.Errorf("unexpected length\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	q.Dequeue(ctx)
+	q.Dequeue(ctx)
+	if _, ok := q.Dequeue(ctx); ok {
+		t.Error("expected Dequeue to stop when the context is done")
+	}
+}
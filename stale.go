@@ -0,0 +1,83 @@
+package codeownerizer
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// TeamSlug derives the slug GitHub gives a team from its name.
+func TeamSlug(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// StaleEntry is a CODEOWNERS owner that no longer refers to the intended team.
+type StaleEntry struct {
+	Repo       string `json:"repo"`
+	Path       string `json:"path"`
+	LineNumber int    `json:"line_number"`
+	Pattern    string `json:"pattern"`
+	Owner      string `json:"owner"`
+	// Replacement is the owner the entry should be replaced with.
+	Replacement string `json:"replacement"`
+}
+
+// FindStaleTeamEntries finds the CODEOWNERS entries still using the old slug of
+// a renamed team in the repositories the team has access to.
+func FindStaleTeamEntries(ctx context.Context, api *github.Client, org string, oldSlug string, newSlug string) ([]StaleEntry, error) {
+	repos, err := ListTeamRepos(ctx, api, org, newSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	oldOwner := "@" + org + "/" + oldSlug
+	var entries []StaleEntry
+	for _, repo := range repos {
+		file, err := FetchCodeownersFile(ctx, api, repo.GetOwner().GetLogin(), repo.GetName(), "")
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		ruleset, err := file.Ruleset()
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		for _, rule := range ruleset {
+			for _, owner := range rule.Owners {
+				if !strings.EqualFold(owner.String(), oldOwner) {
+					continue
+				}
+				entries = append(entries, StaleEntry{
+					Repo:        repo.GetFullName(),
+					Path:        file.Path,
+					LineNumber:  rule.LineNumber,
+					Pattern:     rule.RawPattern(),
+					Owner:       owner.String(),
+					Replacement: "@" + org + "/" + newSlug,
+				})
+			}
+		}
+	}
+	return entries, nil
+}
+
+// NewStaleTeamReporter returns a TeamRenamedFunc that logs the CODEOWNERS
+// entries left stale by the rename.
+func NewStaleTeamReporter(api *github.Client) TeamRenamedFunc {
+	return func(ctx context.Context, org string, oldSlug string, newSlug string) error {
+		entries, err := FindStaleTeamEntries(ctx, api, org, oldSlug, newSlug)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			log.Printf("%s:%s:%d: %s is stale, the team was renamed to %s\n", entry.Repo, entry.Path, entry.LineNumber, entry.Owner, entry.Replacement)
+		}
+		return nil
+	}
+}
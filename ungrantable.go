@@ -0,0 +1,232 @@
+package codeownerizer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/grezar/codeownerizer/codeownersfile"
+	"github.com/hmarr/codeowners"
+)
+
+// UngrantableIssueTitle is the title of the issue listing the owners that
+// cannot be granted.
+const UngrantableIssueTitle = "CODEOWNERS lists owners that cannot be granted"
+
+// UngrantableOwner is a code owner that can never be granted access, such as a
+// deleted team, a deleted or suspended user, a user who is not a member of the
+// organization, or an email that does not resolve to a user.
+type UngrantableOwner struct {
+	Owner  string    `json:"owner"`
+	Reason string    `json:"reason"`
+	Rules  []RuleRef `json:"rules"`
+	// Aliases lists the aliases the owner is listed through. Such an owner has
+	// to be removed or replaced in the alias file rather than in CODEOWNERS.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// FindUngrantableOwners checks that every owner of the ruleset refers to an
+// existing team or an active user who is a member of the organization.
+// Ignored owners are not checked. Owners that cannot be checked are logged and
+// left out.
+func FindUngrantableOwners(ctx context.Context, api *github.Client, org string, ruleset codeowners.Ruleset, opts *Options) ([]UngrantableOwner, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	expanded, err := opts.expandRuleset(ruleset)
+	if err != nil {
+		return nil, err
+	}
+	sources := opts.Aliases.sources(ruleset, opts.isIgnored)
+	ruleset = expanded
+
+	var ungrantable []UngrantableOwner
+	for _, owner := range uniqueOwners(rulesetOwners(ruleset)) {
+		if opts.isIgnored(owner.String()) {
+			continue
+		}
+		reason, err := ungrantableReason(ctx, api, org, owner)
+		if err != nil {
+			log.Printf("%s: %s\n", owner, err.Error())
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+		ungrantable = append(ungrantable, UngrantableOwner{
+			Owner:   owner.String(),
+			Reason:  reason,
+			Rules:   ownerRules(ruleset, owner.String()),
+			Aliases: sources[strings.ToLower(owner.String())],
+		})
+	}
+	return ungrantable, nil
+}
+
+// ungrantableReason explains why the owner cannot be granted, or returns an
+// empty string when it can.
+func ungrantableReason(ctx context.Context, api *github.Client, org string, owner codeowners.Owner) (string, error) {
+	switch owner.Type {
+	case codeowners.TeamOwner:
+		teamOrg, slug, _ := strings.Cut(strings.TrimPrefix(owner.String(), "@"), "/")
+		_, resp, err := api.Teams.GetTeamBySlug(ctx, teamOrg, slug)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "team does not exist", nil
+		}
+		return "", err
+	case codeowners.UsernameOwner:
+		return userUngrantableReason(ctx, api, org, strings.TrimPrefix(owner.String(), "@"))
+	case codeowners.EmailOwner:
+		username, err := resolveEmailOwner(ctx, api, owner.String())
+		if err != nil {
+			return err.Error(), nil
+		}
+		return userUngrantableReason(ctx, api, org, username)
+	default:
+		return fmt.Sprintf("unknown owner type: %s", owner.Type), nil
+	}
+}
+
+// userUngrantableReason checks that the user exists, is not suspended and is a
+// member of the organization, since users who left it keep their account.
+func userUngrantableReason(ctx context.Context, api *github.Client, org string, username string) (string, error) {
+	user, resp, err := api.Users.Get(ctx, username)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "user does not exist", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if user.SuspendedAt != nil {
+		return "user is suspended", nil
+	}
+	member, _, err := api.Organizations.IsMember(ctx, org, username)
+	if err != nil {
+		return "", err
+	}
+	if !member {
+		return "not an organization member", nil
+	}
+	return "", nil
+}
+
+// ownerRules lists the rules that list the owner.
+func ownerRules(ruleset codeowners.Ruleset, owner string) []RuleRef {
+	var rules []RuleRef
+	for _, rule := range ruleset {
+		for _, o := range rule.Owners {
+			if o.String() == owner {
+				rules = append(rules, RuleRef{Pattern: rule.RawPattern(), LineNumber: rule.LineNumber})
+				break
+			}
+		}
+	}
+	return rules
+}
+
+// UngrantableMarkdown renders the owners as a Markdown table.
+func UngrantableMarkdown(owners []UngrantableOwner) string {
+	var b strings.Builder
+	b.WriteString("| Owner | Reason | Rules |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, owner := range owners {
+		var rules []string
+		for _, rule := range owner.Rules {
+			rules = append(rules, fmt.Sprintf("`%s` (line %d)", rule.Pattern, rule.LineNumber))
+		}
+		name := fmt.Sprintf("`%s`", owner.Owner)
+		if len(owner.Aliases) > 0 {
+			name += fmt.Sprintf(" (through %s)", codeList(owner.Aliases))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", name, owner.Reason, strings.Join(rules, ", "))
+	}
+	return b.String()
+}
+
+// SyncUngrantableIssue opens or updates the issue listing the ungrantable
+// owners of the repository, and closes it once there are none.
+func SyncUngrantableIssue(ctx context.Context, api *github.Client, org string, repo string, owners []UngrantableOwner) error {
+	if len(owners) == 0 {
+		return CloseIssue(ctx, api, org, repo, UngrantableIssueTitle, "All code owners can be granted again.")
+	}
+	body := "The following owners in CODEOWNERS can never be granted access, so the rules " +
+		"listing them may not be approvable. Remove or replace them, in the alias file " +
+		"for the owners listed through an alias.\n\n" + UngrantableMarkdown(owners)
+	_, err := UpsertIssue(ctx, api, org, repo, UngrantableIssueTitle, body)
+	return err
+}
+
+// FixUngrantableOwners opens or updates a pull request that rewrites the
+// CODEOWNERS file on the default branch. Owners in the mapping are replaced
+// by their new owner, and the others are removed. An owner mapped to an empty
+// string is removed as well. Owners only listed through aliases are not in
+// CODEOWNERS, so the pull request only notes that the alias file needs
+// editing. It returns nil when the file needs no change.
+func FixUngrantableOwners(ctx context.Context, api *github.Client, org string, repo string, owners []UngrantableOwner, mapping map[string]string) (*github.PullRequest, error) {
+	if len(owners) == 0 {
+		return nil, nil
+	}
+	file, err := FetchCodeownersFile(ctx, api, org, repo, "")
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := make(map[string]string, len(mapping))
+	for owner, replacement := range mapping {
+		normalized[strings.ToLower(owner)] = replacement
+	}
+
+	parsed := codeownersfile.ParseBytes(file.Content)
+	replacements := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("This pull request updates the owners in CODEOWNERS that can never be granted access.\n\n")
+	for _, owner := range owners {
+		if len(owner.Aliases) > 0 {
+			fmt.Fprintf(&b, "- `%s` is listed through %s and has to be removed or replaced in the alias file: %s\n", owner.Owner, codeList(owner.Aliases), owner.Reason)
+			if !listsOwner(parsed, owner.Owner) {
+				continue
+			}
+		}
+		replacement := normalized[strings.ToLower(owner.Owner)]
+		replacements[owner.Owner] = replacement
+		if replacement == "" {
+			fmt.Fprintf(&b, "- Remove `%s`: %s\n", owner.Owner, owner.Reason)
+		} else {
+			fmt.Fprintf(&b, "- Replace `%s` with `%s`: %s\n", owner.Owner, replacement, owner.Reason)
+		}
+	}
+
+	content := RewriteOwners(file.Content, replacements)
+	if string(content) == string(file.Content) {
+		return nil, nil
+	}
+	return UpsertPullRequest(ctx, api, org, repo, file, content, &PullRequestOptions{
+		Branch:        "codeownerizer/fix-ungrantable-owners",
+		Title:         "Fix CODEOWNERS owners that cannot be granted",
+		Body:          b.String(),
+		CommitMessage: "Fix CODEOWNERS owners that cannot be granted",
+	})
+}
+
+// listsOwner reports whether a rule of the file lists the owner itself.
+func listsOwner(file *codeownersfile.File, owner string) bool {
+	for _, line := range file.Rules() {
+		if line.HasOwner(owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeList formats the values as a comma-separated list of Markdown code spans.
+func codeList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "`" + value + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,216 @@
+package codeownerizer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+	"github.com/migueleliasweb/go-github-mock/src/mock"
+)
+
+func TestFixUngrantableOwners(t *testing.T) {
+	content := "# Platform\n* @org/team1 @org/gone @org/broken\n/docs/ @user1   @suspended # docs\n/src/ @user1 @left\n"
+	ruleset, err := codeowners.ParseFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var updated github.RepositoryContentFileOptions
+	var created github.NewPullRequest
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsTeamsByOrgByTeamSlug,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/gone") {
+					mock.WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				// An owner that cannot be checked does not stop the others.
+				if strings.HasSuffix(r.URL.Path, "/broken") {
+					mock.WriteError(w, http.StatusInternalServerError, "Server Error")
+					return
+				}
+				_, _ = w.Write(mock.MustMarshal(github.Team{Slug: github.Ptr("team1")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetUsersByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user := github.User{Login: github.Ptr(path.Base(r.URL.Path))}
+				if strings.HasSuffix(r.URL.Path, "/suspended") {
+					user = github.User{Login: github.Ptr("suspended"), SuspendedAt: &github.Timestamp{Time: time.Now()}}
+				}
+				_, _ = w.Write(mock.MustMarshal(user))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsMembersByOrgByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// @left has left the organization but still has an account.
+				if strings.HasSuffix(r.URL.Path, "/left") {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The branch of the pull request does not exist yet.
+				if r.URL.Query().Get("ref") != "" {
+					mock.WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				_, _ = w.Write(mock.MustMarshal(github.RepositoryContent{
+					Type:     github.Ptr("file"),
+					Encoding: github.Ptr(""),
+					Content:  github.Ptr(content),
+					SHA:      github.Ptr("blob"),
+				}))
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.GetReposByOwnerByRepo,
+			github.Repository{DefaultBranch: github.Ptr("main")},
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetReposGitRefByOwnerByRepoByRef,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/heads/main") {
+					mock.WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				_, _ = w.Write(mock.MustMarshal(github.Reference{
+					Ref:    github.Ptr("refs/heads/main"),
+					Object: &github.GitObject{SHA: github.Ptr("commit")},
+				}))
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.PostReposGitRefsByOwnerByRepo,
+			github.Reference{},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PutReposContentsByOwnerByRepoByPath,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+					t.Error(err)
+				}
+				_, _ = w.Write(mock.MustMarshal(github.RepositoryContentResponse{}))
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.GetReposPullsByOwnerByRepo,
+			[]github.PullRequest{},
+		),
+		mock.WithRequestMatchHandler(
+			mock.PostReposPullsByOwnerByRepo,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+					t.Error(err)
+				}
+				_, _ = w.Write(mock.MustMarshal(github.PullRequest{Number: github.Ptr(1)}))
+			}),
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+
+	owners, err := FindUngrantableOwners(ctx, c, "org", ruleset, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOwners := []UngrantableOwner{
+		{Owner: "@org/gone", Reason: "team does not exist", Rules: []RuleRef{{Pattern: "*", LineNumber: 2}}},
+		{Owner: "@suspended", Reason: "user is suspended", Rules: []RuleRef{{Pattern: "/docs/", LineNumber: 3}}},
+		{Owner: "@left", Reason: "not an organization member", Rules: []RuleRef{{Pattern: "/src/", LineNumber: 4}}},
+	}
+	if diff := cmp.Diff(wantOwners, owners); diff != "" {
+		t.Errorf("unexpected ungrantable owners\n%s", diff)
+	}
+
+	if _, err := FixUngrantableOwners(ctx, c, "org", "repo", owners, map[string]string{"@org/gone": "@org/team2"}); err != nil {
+		t.Fatal(err)
+	}
+	wantContent := "# Platform\n* @org/team1 @org/team2 @org/broken\n/docs/ @user1 # docs\n/src/ @user1\n"
+	if diff := cmp.Diff(wantContent, string(updated.Content)); diff != "" {
+		t.Errorf("unexpected CODEOWNERS\n%s", diff)
+	}
+	if diff := cmp.Diff("blob", updated.GetSHA()); diff != "" {
+		t.Errorf("unexpected blob SHA\n%s", diff)
+	}
+	if diff := cmp.Diff("codeownerizer/fix-ungrantable-owners", created.GetHead()); diff != "" {
+		t.Errorf("unexpected head branch\n%s", diff)
+	}
+}
+
+func TestFixUngrantableOwnersThroughAlias(t *testing.T) {
+	content := "* @backend @user1\n"
+	ruleset, err := codeowners.ParseFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockedHTTPClient := mock.NewMockedHTTPClient(
+		mock.WithRequestMatchHandler(
+			mock.GetUsersByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/deaduser") {
+					mock.WriteError(w, http.StatusNotFound, "Not Found")
+					return
+				}
+				_, _ = w.Write(mock.MustMarshal(github.User{Login: github.Ptr("user1")}))
+			}),
+		),
+		mock.WithRequestMatchHandler(
+			mock.GetOrgsMembersByOrgByUsername,
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+		),
+		mock.WithRequestMatch(
+			mock.GetReposContentsByOwnerByRepoByPath,
+			github.RepositoryContent{
+				Type:     github.Ptr("file"),
+				Encoding: github.Ptr(""),
+				Content:  github.Ptr(content),
+				SHA:      github.Ptr("blob"),
+			},
+		),
+	)
+	c := github.NewClient(mockedHTTPClient)
+	ctx := context.Background()
+
+	owners, err := FindUngrantableOwners(ctx, c, "org", ruleset, &Options{
+		Aliases: Aliases{"@backend": {"@deaduser"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOwners := []UngrantableOwner{
+		{Owner: "@deaduser", Reason: "user does not exist", Rules: []RuleRef{{Pattern: "*", LineNumber: 1}}, Aliases: []string{"@backend"}},
+	}
+	if diff := cmp.Diff(wantOwners, owners); diff != "" {
+		t.Errorf("unexpected ungrantable owners\n%s", diff)
+	}
+	if !strings.Contains(UngrantableMarkdown(owners), "| `@deaduser` (through `@backend`) |") {
+		t.Errorf("expected the alias in the table, got\n%s", UngrantableMarkdown(owners))
+	}
+
+	// @deaduser is not in CODEOWNERS, so there is nothing to rewrite.
+	pull, err := FixUngrantableOwners(ctx, c, "org", "repo", owners, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pull != nil {
+		t.Errorf("expected no pull request, got %v", pull)
+	}
+}
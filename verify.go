@@ -0,0 +1,93 @@
+package codeownerizer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v69/github"
+	"github.com/hmarr/codeowners"
+)
+
+// invitationRoles maps base permissions to the role names that invitations
+// report them as.
+var invitationRoles = map[string]string{
+	"pull": "read",
+	"push": "write",
+}
+
+// Convergence is the result of checking whether a repository has converged,
+// that is, whether granting its owners again would change nothing.
+type Convergence struct {
+	// Remaining are the actions that are still needed, for owners whose access
+	// is still below the target.
+	Remaining []Action `json:"remaining"`
+	// Pending are the actions for users who were invited to the repository
+	// with the target permission and have not accepted yet. They need no
+	// further grant.
+	Pending []Action `json:"pending"`
+}
+
+// Converged reports whether no owner is left below the target.
+func (c *Convergence) Converged() bool {
+	return len(c.Remaining) == 0
+}
+
+// VerifyConvergence reads the access to the repository again and checks that
+// every owner of the rules has the target permission, without granting
+// anything. It is meant to run after Reconcile, or on its own as a check.
+func VerifyConvergence(ctx context.Context, api *github.Client, org string, repo string, ruleset codeowners.Ruleset, opts *Options) (*Convergence, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	actions, err := PlanRuleset(ctx, api, org, repo, ruleset, opts)
+	if err != nil {
+		return nil, err
+	}
+	return PlanConvergence(ctx, api, org, repo, actions, opts)
+}
+
+// PlanConvergence checks whether a repository has converged from the actions
+// just planned for it, which saves planning again when none of them was
+// applied. Users invited with the target permission are pending rather than
+// remaining.
+func PlanConvergence(ctx context.Context, api *github.Client, org string, repo string, actions []Action, opts *Options) (*Convergence, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	convergence := &Convergence{}
+	invited, err := listInvitations(ctx, opts.backend(api), org, repo, actions)
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		if action.Type == ActionGrantUser && isInvited(invited, action) {
+			convergence.Pending = append(convergence.Pending, action)
+			continue
+		}
+		convergence.Remaining = append(convergence.Remaining, action)
+	}
+	return convergence, nil
+}
+
+// listInvitations lists the pending invitations when any user is to be
+// granted and the backend knows them.
+func listInvitations(ctx context.Context, backend Backend, org string, repo string, actions []Action) (map[string]string, error) {
+	invitations, ok := backend.(InvitationBackend)
+	if !ok {
+		return nil, nil
+	}
+	for _, action := range actions {
+		if action.Type == ActionGrantUser {
+			return invitations.ListInvitations(ctx, org, repo)
+		}
+	}
+	return nil, nil
+}
+
+func isInvited(invited map[string]string, action Action) bool {
+	permission, ok := invited[strings.ToLower(action.Principal)]
+	if !ok {
+		return false
+	}
+	return permission == action.Permission || permission == invitationRoles[action.Permission]
+}